@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// emptyTreeHash is the hash of the empty tree, the base of a root commit.
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// errNoCommit is returned when there is no commit to amend.
+var errNoCommit = errors.New("there is no commit to amend")
+
+// amendBase returns what the amended commit is compared with: the parent of HEAD,
+// or the empty tree when HEAD is a root commit.
+func amendBase(ctx context.Context) (string, error) {
+	if err := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD").Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", errNoCommit
+	}
+	output, err := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD^").Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return emptyTreeHash, nil
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// amendDiff runs git diff --staged with args against the parent of HEAD, which
+// covers both the changes of HEAD and the staged changes.
+func amendDiff(ctx context.Context, args ...string) (string, error) {
+	base, err := amendBase(ctx)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, "git", append(append([]string{"diff", "--staged"}, args...), base)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+func getAmendDiff(ctx context.Context) (string, error) {
+	return amendDiff(ctx)
+}
+
+func getAmendFileList(ctx context.Context) (string, error) {
+	output, err := amendDiff(ctx, "--name-only")
+	return strings.TrimSpace(output), err
+}
+
+func getAmendDiffStat(ctx context.Context) (string, error) {
+	output, err := amendDiff(ctx, "--stat")
+	return strings.TrimSpace(output), err
+}
+
+// getHeadMessage returns the full message of HEAD.
+func getHeadMessage(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "log", "-1", "--format=%B", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the message of HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gitCommitAmend replaces HEAD with a commit of the index and message.
+func gitCommitAmend(ctx context.Context, message string) error {
+	// Use --no-verify to skip pre-commit hooks since we already ran them
+	cmd := exec.CommandContext(ctx, "git", "commit", "--amend", "--no-verify", "-m", message)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
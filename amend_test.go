@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestMainAmend(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainAmend" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+		if os.Getenv("AMEND_ROOT") == "" {
+			if err := exec.Command("git", "commit", "-m", "chore: initial").Run(); err != nil {
+				panic(err)
+			}
+			if err := os.WriteFile("test.txt", []byte("test content\nmore"), 0o644); err != nil {
+				panic(err)
+			}
+			if err := exec.Command("git", "add", "test.txt").Run(); err != nil {
+				panic(err)
+			}
+		}
+		if err := exec.Command("git", "commit", "-m", "wip").Run(); err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile("second.txt", []byte("second"), 0o644); err != nil {
+			panic(err)
+		}
+		if err := exec.Command("git", "add", "second.txt").Run(); err != nil {
+			panic(err)
+		}
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				for _, want := range strings.Split(os.Getenv("AMEND_WANT"), ",") {
+					if !strings.Contains(prompt, want) {
+						return "", errors.New("the prompt should contain the changes of HEAD and the staged changes: " + want)
+					}
+				}
+				if !strings.Contains(prompt, "---\nwip\n---") {
+					return "", errors.New("the previous message is missing")
+				}
+				return "feat: テストファイルを追加", nil
+			}), nil
+		}
+		runPreCommit = func(ctx context.Context) error { return nil }
+		runMainWithStdin("")
+		out, err := exec.Command("git", "log", "--format=%s").Output()
+		if err != nil {
+			panic(err)
+		}
+		os.Stdout.WriteString("log: " + strings.Join(strings.Split(strings.TrimSpace(string(out)), "\n"), " | ") + "\n")
+		return
+	}
+
+	t.Setenv("AMEND_WANT", "+more,+second")
+	output := runTestSubprocess(t, "TestMainAmend", "-y", "-amend")
+	if !strings.Contains(output, "log: feat: テストファイルを追加 | chore: initial\n") {
+		t.Errorf("Expected HEAD to be amended, got '%s'", output)
+	}
+
+	t.Setenv("AMEND_ROOT", "1")
+	t.Setenv("AMEND_WANT", "+test content,+second")
+	output = runTestSubprocess(t, "TestMainAmend", "-y", "-amend")
+	if !strings.Contains(output, "log: feat: テストファイルを追加\n") {
+		t.Errorf("Expected the root commit to be amended, got '%s'", output)
+	}
+}
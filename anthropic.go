@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com"
+	defaultAnthropicModel     = "claude-sonnet-4-5"
+	defaultAnthropicMaxTokens = 1024
+	anthropicAPIVersion       = "2023-06-01"
+)
+
+// AnthropicExecutor implements AIExecutor by calling the Anthropic Messages API directly.
+type AnthropicExecutor struct {
+	APIKey    string
+	Model     string
+	MaxTokens int
+	BaseURL   string
+	Client    *http.Client
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+}
+
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// newAnthropicExecutor builds an AnthropicExecutor from the configured settings.
+// ANTHROPIC_API_KEY is required; a non-empty model overrides settings.Model.
+func newAnthropicExecutor(settings AnthropicConfig, model string) (*AnthropicExecutor, error) {
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("ANTHROPIC_API_KEY is not set")
+	}
+	if settings.MaxTokens <= 0 {
+		return nil, fmt.Errorf("invalid anthropic.max_tokens: %d", settings.MaxTokens)
+	}
+
+	e := &AnthropicExecutor{
+		APIKey:    apiKey,
+		Model:     settings.Model,
+		MaxTokens: settings.MaxTokens,
+		BaseURL:   settings.BaseURL,
+	}
+	if model != "" {
+		e.Model = model
+	}
+	return e, nil
+}
+
+// Execute sends the prompt as a single user message and returns the text of the reply.
+func (e *AnthropicExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	body := anthropicRequest{
+		Model:     e.Model,
+		MaxTokens: e.MaxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+	}
+	headers := map[string]string{
+		"x-api-key":         e.APIKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+
+	var resp anthropicResponse
+	url := strings.TrimSuffix(e.BaseURL, "/") + "/v1/messages"
+	if err := postJSON(ctx, e.Client, url, headers, body, &resp); err != nil {
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) {
+			var apiErr anthropicErrorResponse
+			if json.Unmarshal([]byte(statusErr.Body), &apiErr) == nil && apiErr.Error.Message != "" {
+				statusErr.Message = apiErr.Error.Type + ": " + apiErr.Error.Message
+			}
+			return "", classifyStatus(statusErr, fmt.Errorf("anthropic API error: %w", statusErr))
+		}
+		return "", fmt.Errorf("anthropic API request failed: %w", err)
+	}
+
+	var texts []string
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(texts, ""))
+
+	if resp.StopReason == "refusal" {
+		return "", &ExecutorError{Kind: errRefused, Detail: firstLine(text)}
+	}
+	if err := classifyOutput(text, nil); err != nil {
+		return "", err
+	}
+	return text, nil
+}
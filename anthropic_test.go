@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicExecutor(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		response      string
+		want          string
+		wantError     bool
+		errorContains string
+	}{
+		{
+			name:     "text blocks are joined",
+			status:   http.StatusOK,
+			response: `{"content":[{"type":"text","text":"feat: 新機能"},{"type":"text","text":"を追加\n"}],"stop_reason":"end_turn"}`,
+			want:     "feat: 新機能を追加",
+		},
+		{
+			name:     "non-text blocks are ignored",
+			status:   http.StatusOK,
+			response: `{"content":[{"type":"thinking","text":"hmm"},{"type":"text","text":"fix: バグ修正"}]}`,
+			want:     "fix: バグ修正",
+		},
+		{
+			name:          "api error is reported",
+			status:        http.StatusTooManyRequests,
+			response:      `{"type":"error","error":{"type":"rate_limit_error","message":"Rate limited"}}`,
+			wantError:     true,
+			errorContains: "rate_limit_error: Rate limited",
+		},
+		{
+			name:          "non-json error body",
+			status:        http.StatusBadGateway,
+			response:      "bad gateway",
+			wantError:     true,
+			errorContains: "status 502: bad gateway",
+		},
+		{
+			name:          "refusal is reported",
+			status:        http.StatusOK,
+			response:      `{"content":[{"type":"text","text":"I can't help with that."}],"stop_reason":"refusal"}`,
+			wantError:     true,
+			errorContains: "request refused by the model: I can't help with that.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got anthropicRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/messages" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if r.Header.Get("x-api-key") != "test-key" {
+					t.Errorf("x-api-key = %q, want test-key", r.Header.Get("x-api-key"))
+				}
+				if r.Header.Get("anthropic-version") != anthropicAPIVersion {
+					t.Errorf("anthropic-version = %q", r.Header.Get("anthropic-version"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			executor := &AnthropicExecutor{
+				APIKey:    "test-key",
+				Model:     "test-model",
+				MaxTokens: 256,
+				BaseURL:   server.URL + "/",
+			}
+			result, err := executor.Execute(context.Background(), "test prompt")
+
+			if got.Model != "test-model" || got.MaxTokens != 256 || len(got.Messages) != 1 || got.Messages[0].Content != "test prompt" {
+				t.Errorf("unexpected request body: %+v", got)
+			}
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("Execute() expected error but got none")
+				}
+				if !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Execute() error = %v, want error containing %s", err, tt.errorContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Execute() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAnthropicExecutor(t *testing.T) {
+	settings := defaultConfig().Anthropic
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := newAnthropicExecutor(settings, ""); err == nil {
+		t.Error("newAnthropicExecutor() expected error without API key")
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "key")
+	settings = AnthropicConfig{Model: "config-model", MaxTokens: 2048, BaseURL: "http://localhost:9999"}
+
+	e, err := newAnthropicExecutor(settings, "")
+	if err != nil {
+		t.Fatalf("newAnthropicExecutor() unexpected error = %v", err)
+	}
+	if e.APIKey != "key" || e.BaseURL != "http://localhost:9999" || e.Model != "config-model" || e.MaxTokens != 2048 {
+		t.Errorf("unexpected executor settings: %+v", e)
+	}
+
+	e, err = newAnthropicExecutor(settings, "flag-model")
+	if err != nil {
+		t.Fatalf("newAnthropicExecutor() unexpected error = %v", err)
+	}
+	if e.Model != "flag-model" {
+		t.Errorf("Model = %q, want flag-model", e.Model)
+	}
+
+	settings.MaxTokens = 0
+	if _, err := newAnthropicExecutor(settings, ""); err == nil {
+		t.Error("newAnthropicExecutor() expected error for invalid max tokens")
+	}
+}
+
+func TestAnthropicExecutorContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"feat: x"}]}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	executor := &AnthropicExecutor{APIKey: "k", Model: "m", MaxTokens: 1, BaseURL: server.URL}
+	if _, err := executor.Execute(ctx, "test prompt"); err == nil {
+		t.Error("Execute() expected error when context is canceled, but got none")
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// bytesPerToken is the rough number of bytes of code or English text per token.
+const bytesPerToken = 4
+
+// defaultMaxDiffTokens is the diff budget of backends without a configured one.
+const defaultMaxDiffTokens = 12000
+
+// defaultDiffBudgets are the diff budgets, in estimated tokens, of the built-in
+// backends. codex receives the prompt as a command line argument, which the OS
+// limits to 128 KiB; the API backends are sized for small local models.
+var defaultDiffBudgets = map[string]int{
+	"claude":    50000,
+	"gemini":    100000,
+	"codex":     12000,
+	"anthropic": 50000,
+	"openai":    8000,
+}
+
+// diffTruncatedNote marks a diff that was cut because it could not be split into files.
+const diffTruncatedNote = "\n...(diff truncated for size)..."
+
+// lowPriorityFiles classify files whose diff says little about the intent of a change.
+// They are the first to be left out when the diff exceeds the budget.
+var lowPriorityFiles = []struct {
+	kind     string
+	patterns []string
+}{
+	{"lockfile", []string{
+		"**/package-lock.json", "**/npm-shrinkwrap.json", "**/yarn.lock", "**/pnpm-lock.yaml", "**/bun.lockb",
+		"**/go.sum", "**/Cargo.lock", "**/Gemfile.lock", "**/composer.lock", "**/poetry.lock", "**/Pipfile.lock",
+		"**/uv.lock", "**/*.lock",
+	}},
+	{"vendored", []string{"**/vendor/**", "**/node_modules/**", "**/third_party/**"}},
+	{"generated", []string{"**/*.pb.go", "**/*_pb2.py", "**/*.pb.*", "**/*_generated.*", "**/*.generated.*", "**/*.gen.go", "**/*_gen.go"}},
+	{"minified", []string{"**/*.min.js", "**/*.min.css", "**/*.js.map", "**/*.css.map"}},
+	{"snapshot", []string{"**/__snapshots__/**", "**/*.snap"}},
+}
+
+// generatedMarkerPattern matches the header added lines of generated code carry,
+// e.g. "// Code generated by protoc-gen-go. DO NOT EDIT."
+var generatedMarkerPattern = regexp.MustCompile(`(?m)^\+.*(Code generated .*DO NOT EDIT|@generated\b)`)
+
+// diffFile is the diff of one file split into its header and hunks.
+type diffFile struct {
+	Path   string
+	Header string
+	Hunks  []string
+	// Kind is the lowPriorityFiles kind of the file, empty for source files.
+	Kind string
+}
+
+// parseDiffFile splits the diff of one file into its header and hunks.
+func parseDiffFile(file string) diffFile {
+	var f diffFile
+	var header, hunk strings.Builder
+	oldPath := ""
+	for _, line := range strings.SplitAfter(file, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			if hunk.Len() > 0 {
+				f.Hunks = append(f.Hunks, hunk.String())
+				hunk.Reset()
+			}
+			hunk.WriteString(line)
+		case hunk.Len() > 0:
+			hunk.WriteString(line)
+		default:
+			header.WriteString(line)
+			switch {
+			case strings.HasPrefix(line, "+++ b/"):
+				f.Path = strings.TrimSpace(strings.TrimPrefix(line, "+++ b/"))
+			case strings.HasPrefix(line, "--- a/"):
+				oldPath = strings.TrimSpace(strings.TrimPrefix(line, "--- a/"))
+			case strings.HasPrefix(line, "diff --git ") && f.Path == "":
+				// Binary files and renames have no ---/+++ lines
+				if i := strings.LastIndex(line, " b/"); i != -1 {
+					f.Path = strings.TrimSpace(line[i+len(" b/"):])
+				}
+			}
+		}
+	}
+	if hunk.Len() > 0 {
+		f.Hunks = append(f.Hunks, hunk.String())
+	}
+	f.Header = header.String()
+	if f.Path == "" {
+		f.Path = oldPath
+	}
+	f.Kind = classifyDiffFile(f.Path, file)
+	return f
+}
+
+// classifyDiffFile returns the lowPriorityFiles kind of the file at path, or "" for a
+// source file. Files whose added lines carry a generated-code marker are "generated".
+func classifyDiffFile(path, diff string) string {
+	for _, class := range lowPriorityFiles {
+		for _, pattern := range class.patterns {
+			if matchPathGlob(pattern, path) {
+				return class.kind
+			}
+		}
+	}
+	if generatedMarkerPattern.MatchString(diff) {
+		return "generated"
+	}
+	return ""
+}
+
+// text returns the whole diff of the file.
+func (f diffFile) text() string {
+	return f.Header + strings.Join(f.Hunks, "")
+}
+
+// label names the file in omission notes.
+func (f diffFile) label() string {
+	if f.Path == "" {
+		return "diff"
+	}
+	return f.Path
+}
+
+// fit returns as many whole hunks of the file as fit in budget tokens, and a note
+// describing what was left out. When not even one hunk fits, the first one is cut so
+// that the change is not invisible.
+func (f diffFile) fit(budget int) (string, string) {
+	whole := f.text()
+	if estimateTokens(whole) <= budget {
+		return whole, ""
+	}
+	if len(f.Hunks) == 0 {
+		return cutToTokens(whole, budget) + diffTruncatedNote, f.label() + ": truncated"
+	}
+
+	var b strings.Builder
+	b.WriteString(f.Header)
+	used := estimateTokens(f.Header)
+	var kept, added, deleted int
+	for _, hunk := range f.Hunks {
+		if tokens := estimateTokens(hunk); used+tokens <= budget {
+			b.WriteString(hunk)
+			used += tokens
+			kept++
+			continue
+		}
+		a, d := countChangedLines(hunk)
+		added += a
+		deleted += d
+	}
+	if kept == 0 {
+		if room := budget - used; room > 0 {
+			b.WriteString(cutToTokens(f.Hunks[0], room) + hunkTruncatedNote)
+			return b.String(), fmt.Sprintf("%s: first hunk truncated, %d of %d hunks omitted (+%d -%d lines)", f.label(), len(f.Hunks)-1, len(f.Hunks), added, deleted)
+		}
+		return "", fmt.Sprintf("%s: +%d -%d lines omitted", f.label(), added, deleted)
+	}
+	return b.String(), fmt.Sprintf("%s: %d of %d hunks omitted (+%d -%d lines)", f.label(), len(f.Hunks)-kept, len(f.Hunks), added, deleted)
+}
+
+// fitDiffToBudget reduces diff to about budget estimated tokens and returns one note
+// per file that was shortened or left out, in diff order. Low-priority files such as
+// lockfiles and generated code are only kept whole with the room source files leave.
+// Source files share the budget evenly, the smallest first so that one large file
+// cannot crowd out the others, and keep whole hunks.
+func fitDiffToBudget(diff string, budget int) (string, []string) {
+	if estimateTokens(diff) <= budget {
+		return diff, nil
+	}
+
+	var files []diffFile
+	var sources, others []int
+	for i, piece := range splitDiffFiles(diff) {
+		f := parseDiffFile(piece)
+		files = append(files, f)
+		if f.Kind == "" {
+			sources = append(sources, i)
+		} else {
+			others = append(others, i)
+		}
+	}
+	bySize := func(indexes []int) {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return len(files[indexes[a]].text()) < len(files[indexes[b]].text())
+		})
+	}
+	bySize(sources)
+	bySize(others)
+
+	kept := make([]string, len(files))
+	notes := make([]string, len(files))
+	remaining := budget
+	for n, i := range sources {
+		share := remaining / (len(sources) - n)
+		kept[i], notes[i] = files[i].fit(share)
+		remaining -= estimateTokens(kept[i])
+	}
+	for _, i := range others {
+		f := files[i]
+		if text := f.text(); estimateTokens(text) <= remaining {
+			kept[i] = text
+			remaining -= estimateTokens(text)
+			continue
+		}
+		added, deleted := 0, 0
+		for _, hunk := range f.Hunks {
+			a, d := countChangedLines(hunk)
+			added += a
+			deleted += d
+		}
+		notes[i] = fmt.Sprintf("%s: %s, +%d -%d lines omitted", f.label(), f.Kind, added, deleted)
+	}
+
+	var omitted []string
+	for _, note := range notes {
+		if note != "" {
+			omitted = append(omitted, note)
+		}
+	}
+	return strings.Join(kept, ""), omitted
+}
+
+// countChangedLines counts the added and deleted lines of a hunk.
+func countChangedLines(hunk string) (added, deleted int) {
+	for _, line := range strings.Split(hunk, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			deleted++
+		}
+	}
+	return added, deleted
+}
+
+// estimateTokens estimates the number of tokens of s: about bytesPerToken ASCII
+// characters per token, and one token per other character, which covers CJK text.
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+bytesPerToken-1)/bytesPerToken + other
+}
+
+// cutToTokens returns the longest prefix of s estimated at no more than tokens tokens.
+func cutToTokens(s string, tokens int) string {
+	ascii, other := 0, 0
+	for i, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+		if (ascii+bytesPerToken-1)/bytesPerToken+other > tokens {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// tokenBudget returns the diff budget, in estimated tokens, for the given models.
+// diff.max_tokens applies to every backend; otherwise each backend uses its entry in
+// diff.budgets, and a list of models uses the smallest budget so that the diff fits
+// whichever backend ends up writing the message. The legacy diff.max_size is
+// converted from bytes.
+func (c DiffConfig) tokenBudget(models []string) int {
+	if c.MaxTokens > 0 {
+		return c.MaxTokens
+	}
+	if c.MaxSize > 0 {
+		return max(c.MaxSize/bytesPerToken, 1)
+	}
+
+	budget := 0
+	for _, spec := range models {
+		for _, model := range splitList(spec) {
+			tokens, ok := c.Budgets[model]
+			if !ok {
+				name, _, _ := parseModelSpec(model)
+				if tokens, ok = c.Budgets[name]; !ok {
+					tokens = defaultMaxDiffTokens
+				}
+			}
+			if budget == 0 || tokens < budget {
+				budget = tokens
+			}
+		}
+	}
+	if budget <= 0 {
+		return defaultMaxDiffTokens
+	}
+	return budget
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"ログイン", 4},
+		{"fix ログイン", 5},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.s); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"0123456789", "ログインの修正"} {
+		cut := cutToTokens(s, 2)
+		if cut == "" || estimateTokens(cut) > 2 || !strings.HasPrefix(s, cut) {
+			t.Errorf("cutToTokens(%q, 2) = %q", s, cut)
+		}
+	}
+}
+
+func TestClassifyDiffFile(t *testing.T) {
+	tests := []struct {
+		path string
+		diff string
+		want string
+	}{
+		{"main.go", "+package main\n", ""},
+		{"web/package-lock.json", "", "lockfile"},
+		{"go.sum", "", "lockfile"},
+		{"vendor/github.com/x/y.go", "", "vendored"},
+		{"web/node_modules/x/index.js", "", "vendored"},
+		{"api/user.pb.go", "", "generated"},
+		{"mock.go", "+// Code generated by mockgen. DO NOT EDIT.\n", "generated"},
+		{"static/app.min.js", "", "minified"},
+		{"src/__snapshots__/App.test.js.snap", "", "snapshot"},
+	}
+	for _, tt := range tests {
+		if got := classifyDiffFile(tt.path, tt.diff); got != tt.want {
+			t.Errorf("classifyDiffFile(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFitDiffToBudget(t *testing.T) {
+	hunk := "+" + strings.Repeat("x", 400) + "\n"
+	source := testFileDiff("main.go", hunk, hunk)
+	other := testFileDiff("util.go", "+y\n")
+	lockfile := testFileDiff("package-lock.json", "+"+strings.Repeat("z", 2000)+"\n-old\n")
+
+	t.Run("diff within the budget is unchanged", func(t *testing.T) {
+		diff := source + other
+		got, omitted := fitDiffToBudget(diff, estimateTokens(diff))
+		if got != diff || omitted != nil {
+			t.Errorf("fitDiffToBudget() = %q, %q", got, omitted)
+		}
+	})
+
+	t.Run("low-priority files are left out first", func(t *testing.T) {
+		got, omitted := fitDiffToBudget(source+lockfile+other, estimateTokens(source+other))
+		if got != source+other {
+			t.Errorf("source files should be kept whole, got %q", got)
+		}
+		if want := []string{"package-lock.json: lockfile, +1 -1 lines omitted"}; !slices.Equal(omitted, want) {
+			t.Errorf("omitted = %q, want %q", omitted, want)
+		}
+	})
+
+	t.Run("source files keep whole hunks", func(t *testing.T) {
+		budget := estimateTokens(source+other) - 10
+		got, omitted := fitDiffToBudget(lockfile+source+other, budget)
+		if estimateTokens(got) > budget {
+			t.Errorf("fitted diff of %d tokens exceeds the budget of %d", estimateTokens(got), budget)
+		}
+		if strings.Count(got, hunk) != 1 || !strings.Contains(got, other) {
+			t.Errorf("expected one whole hunk of main.go and all of util.go, got %q", got)
+		}
+		want := []string{"package-lock.json: lockfile, +1 -1 lines omitted", "main.go: 1 of 2 hunks omitted (+1 -0 lines)"}
+		if !slices.Equal(omitted, want) {
+			t.Errorf("omitted = %q, want %q", omitted, want)
+		}
+	})
+
+	t.Run("oversized hunk is cut", func(t *testing.T) {
+		got, omitted := fitDiffToBudget(source, 80)
+		if !strings.HasPrefix(got, "diff --git a/main.go") || !strings.HasSuffix(got, hunkTruncatedNote) {
+			t.Errorf("fitDiffToBudget() = %q", got)
+		}
+		if len(omitted) != 1 || !strings.HasPrefix(omitted[0], "main.go: first hunk truncated") {
+			t.Errorf("omitted = %q", omitted)
+		}
+	})
+}
+
+func TestTokenBudget(t *testing.T) {
+	diff := defaultConfig().Diff
+	tests := []struct {
+		name     string
+		settings func(*DiffConfig)
+		models   []string
+		want     int
+	}{
+		{"backend budget", nil, []string{"gemini"}, 100000},
+		{"model spec", nil, []string{"openai:http://localhost:11434/v1@qwen"}, 8000},
+		{"smallest of a list", nil, []string{"claude,codex"}, 12000},
+		{"unknown command", nil, []string{"my-llm"}, defaultMaxDiffTokens},
+		{"configured budget", func(c *DiffConfig) { c.Budgets["my-llm"] = 3000 }, []string{"my-llm", "claude"}, 3000},
+		{"max_tokens wins", func(c *DiffConfig) { c.MaxTokens = 500; c.MaxSize = 8000 }, []string{"claude"}, 500},
+		{"legacy max_size", func(c *DiffConfig) { c.MaxSize = 8000 }, []string{"claude"}, 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := diff
+			settings.Budgets = maps.Clone(diff.Budgets)
+			if tt.settings != nil {
+				tt.settings(&settings)
+			}
+			if got := settings.tokenBudget(tt.models); got != tt.want {
+				t.Errorf("tokenBudget(%q) = %d, want %d", tt.models, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// candidateHint returns the extra instruction that makes candidate i differ from the others.
+// The first candidate uses the plain prompt, the second asks for a terse subject-only
+// message and the third for a detailed body.
+func candidateHint(i int, lang string) string {
+	text := promptTextFor(lang)
+	switch i {
+	case 0:
+		return ""
+	case 1, 2:
+		return text.hints[i-1]
+	default:
+		return fmt.Sprintf(text.hintOther, i+1)
+	}
+}
+
+// generateCandidates asks the executor for n commit messages in parallel, each with a
+// different style hint, and returns the distinct non-empty results in candidate order.
+// An error is returned only when every request failed.
+func generateCandidates(ctx context.Context, executor AIExecutor, n int, diff, fileList, stat string, opts promptOptions) ([]string, error) {
+	prompt, err := buildCommitPrompt(diff, fileList, stat, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			raw, err := executor.Execute(ctx, prompt+candidateHint(i, opts.Language))
+			if err != nil {
+				errs[i] = fmt.Errorf("candidate %d: %w", i+1, err)
+				return
+			}
+			messages[i] = extractCommitMessage(raw)
+		}(i)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	seen := make(map[string]bool, n)
+	var candidates []string
+	for i, message := range messages {
+		if errs[i] != nil || message == "" || seen[message] {
+			continue
+		}
+		seen[message] = true
+		candidates = append(candidates, message)
+	}
+	if len(candidates) == 0 {
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+	}
+	return candidates, nil
+}
+
+// filterCandidates drops empty messages.
+func filterCandidates(messages []string) []string {
+	var candidates []string
+	for _, message := range messages {
+		if message != "" {
+			candidates = append(candidates, message)
+		}
+	}
+	return candidates
+}
+
+// printCandidates shows the generated messages, marking the selected one when there are several.
+func printCandidates(candidates []string, selected int) {
+	if len(candidates) == 1 {
+		fmt.Println("\n📝 Generated Commit Message:")
+		fmt.Println("===================================")
+		fmt.Println(candidates[0])
+		fmt.Println("===================================")
+		return
+	}
+
+	fmt.Println("\n📝 Generated Commit Message Candidates:")
+	fmt.Println("===================================")
+	for i, candidate := range candidates {
+		if i > 0 {
+			fmt.Println("-----------------------------------")
+		}
+		marker := " "
+		if i == selected {
+			marker = "▶"
+		}
+		fmt.Printf("%s [%d] %s\n", marker, i+1, strings.ReplaceAll(candidate, "\n", "\n      "))
+	}
+	fmt.Println("===================================")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateCandidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		execute   func(prompt string) (string, error)
+		want      []string
+		wantError bool
+	}{
+		{
+			name: "one candidate per hint",
+			n:    3,
+			execute: func(prompt string) (string, error) {
+				switch {
+				case strings.Contains(prompt, "件名の1行のみ"):
+					return "feat: 簡潔", nil
+				case strings.Contains(prompt, "詳しく説明"):
+					return "前置き\n\nfeat: 詳細\n\n- 本文", nil
+				default:
+					return "feat: 標準", nil
+				}
+			},
+			want: []string{"feat: 標準", "feat: 簡潔", "feat: 詳細\n\n- 本文"},
+		},
+		{
+			name: "duplicates and failures are dropped",
+			n:    3,
+			execute: func(prompt string) (string, error) {
+				if strings.Contains(prompt, "詳しく説明") {
+					return "", errors.New("rate limited")
+				}
+				return "fix: 同じ", nil
+			},
+			want: []string{"fix: 同じ"},
+		},
+		{
+			name: "all failures are reported",
+			n:    2,
+			execute: func(prompt string) (string, error) {
+				return "", errors.New("not logged in")
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				return tt.execute(prompt)
+			})
+			got, err := generateCandidates(context.Background(), executor, tt.n, "diff", "main.go", "stat", promptOptions{})
+			if tt.wantError {
+				if err == nil || !strings.Contains(err.Error(), "candidate 1: not logged in") {
+					t.Errorf("generateCandidates() error = %v, want joined candidate errors", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generateCandidates() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("generateCandidates() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCandidates(t *testing.T) {
+	candidates := filterCandidates([]string{"", "fix: handle failed uploads", "feat: ok"})
+	if !reflect.DeepEqual(candidates, []string{"fix: handle failed uploads", "feat: ok"}) {
+		t.Errorf("filterCandidates() candidates = %q", candidates)
+	}
+}
+
+func TestMainSelectCandidate(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainSelectCandidate" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				if strings.Contains(prompt, "件名の1行のみ") {
+					return "feat: terse candidate", nil
+				}
+				return "feat: default candidate\n\n- with body", nil
+			}), nil
+		}
+		runMainWithStdin("2\ny\n")
+		printLastCommitSubject()
+		return
+	}
+
+	output := runTestSubprocess(t, "TestMainSelectCandidate", "-n", "2")
+	if !strings.Contains(output, "[2] feat: terse candidate") {
+		t.Errorf("Expected candidates to be listed, got '%s'", output)
+	}
+	if !strings.Contains(output, "last commit: feat: terse candidate") {
+		t.Errorf("Expected the second candidate to be committed, got '%s'", output)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// errEmptyResponse is reported when a backend succeeds but returns no output.
+var errEmptyResponse = errors.New("empty response")
+
+// errNoCommitHeader is reported when a response has no conventional commit header,
+// e.g. prose or a refusal the executor did not recognize.
+var errNoCommitHeader = errors.New("no conventional commit header in the response")
+
+// errLintRejected is reported when the message of a response breaks the lint rules.
+// Composite executors prefer another backend's response, but use such a response
+// when no backend does better, so that the repair step can still fix it.
+var errLintRejected = errors.New("the message breaks the commit message rules")
+
+// NamedExecutor pairs an executor with the model name it was created from.
+type NamedExecutor struct {
+	Name     string
+	Executor AIExecutor
+}
+
+// BackendError records why one backend of a composite executor failed.
+type BackendError struct {
+	Name string
+	Err  error
+}
+
+func (e *BackendError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// ChainError is returned when every backend of a composite executor failed.
+type ChainError struct {
+	Failures []*BackendError
+}
+
+func (e *ChainError) Error() string {
+	messages := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		messages = append(messages, failure.Error())
+	}
+	return "all backends failed: " + strings.Join(messages, "; ")
+}
+
+// Unwrap exposes the individual failures to errors.Is and errors.As.
+func (e *ChainError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		errs = append(errs, failure)
+	}
+	return errs
+}
+
+// backendReporter is implemented by composite executors that can tell which
+// backend produced the last response and which ones failed before it.
+type backendReporter interface {
+	LastResult() (backend string, failures []*BackendError)
+}
+
+// backendResult stores the outcome of the last Execute call of a composite executor.
+type backendResult struct {
+	mu           sync.Mutex
+	lastBackend  string
+	lastFailures []*BackendError
+}
+
+func (r *backendResult) record(backend string, failures []*BackendError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastBackend = backend
+	r.lastFailures = failures
+}
+
+// LastResult reports the backend that produced the last response and the failures before it.
+func (r *backendResult) LastResult() (backend string, failures []*BackendError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastBackend, r.lastFailures
+}
+
+// brokenExecutor stands in for a backend of a composite executor that could not be
+// created and fails every call with the error that prevented its creation.
+type brokenExecutor struct {
+	err error
+}
+
+func (e brokenExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	return "", e.err
+}
+
+// acceptFunc decides whether a raw backend response is usable. newExecutor takes one
+// for the kind of response its callers expect: commit messages, diff summaries or
+// split plans.
+type acceptFunc func(output string) error
+
+// acceptNonEmpty accepts any response with text.
+func acceptNonEmpty(output string) error {
+	if strings.TrimSpace(output) == "" {
+		return errEmptyResponse
+	}
+	return nil
+}
+
+// check runs f, or acceptCommitMessage without lint rules when f is nil.
+func (f acceptFunc) check(output string) error {
+	if f == nil {
+		return acceptCommitMessage(output, nil)
+	}
+	return f(output)
+}
+
+// AcceptExecutor applies the acceptance check of the composite executors to a single
+// backend, so that a response is judged the same however many models are listed.
+// A response only the lint rules reject is returned, as composite executors do when
+// no backend does better.
+type AcceptExecutor struct {
+	Executor AIExecutor
+	// Accept validates the response; nil uses acceptCommitMessage without lint rules.
+	Accept acceptFunc
+}
+
+// Execute runs the wrapped executor and checks its response.
+func (e *AcceptExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	output, err := e.Executor.Execute(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if err := e.Accept.check(output); err != nil && !errors.Is(err, errLintRejected) {
+		return "", err
+	}
+	return output, nil
+}
+
+// lintRejected remembers the first response only the lint rules rejected, which a
+// composite executor returns when no backend produced an acceptable response.
+type lintRejected struct {
+	backend string
+	output  string
+}
+
+func (r *lintRejected) offer(backend, output string, err error) {
+	if r.backend == "" && errors.Is(err, errLintRejected) {
+		r.backend, r.output = backend, output
+	}
+}
+
+// without drops the failure of the backend whose response is used after all.
+func (r *lintRejected) without(failures []*BackendError) []*BackendError {
+	kept := make([]*BackendError, 0, len(failures))
+	for _, failure := range failures {
+		if failure.Name != r.backend {
+			kept = append(kept, failure)
+		}
+	}
+	return kept
+}
+
+// FallbackExecutor tries each backend in order and returns the first acceptable response.
+type FallbackExecutor struct {
+	Backends []NamedExecutor
+	// Accept validates each response; nil uses acceptCommitMessage without lint rules.
+	Accept acceptFunc
+
+	backendResult
+}
+
+// Execute runs the backends in order until one of them succeeds.
+func (e *FallbackExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	var failures []*BackendError
+	var rejected lintRejected
+	for _, backend := range e.Backends {
+		output, err := backend.Executor.Execute(ctx, prompt)
+		if err == nil {
+			err = e.Accept.check(output)
+		}
+		if err == nil {
+			e.record(backend.Name, failures)
+			return output, nil
+		}
+		// Stop immediately on interruption instead of starting the next backend
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		rejected.offer(backend.Name, output, err)
+		failures = append(failures, &BackendError{Name: backend.Name, Err: err})
+	}
+
+	if rejected.backend != "" {
+		e.record(rejected.backend, rejected.without(failures))
+		return rejected.output, nil
+	}
+	e.record("", failures)
+	return "", &ChainError{Failures: failures}
+}
+
+// RaceExecutor runs all backends concurrently and returns the first acceptable
+// response, canceling the backends that are still running.
+type RaceExecutor struct {
+	Backends []NamedExecutor
+	// Accept validates each response; nil uses acceptCommitMessage without lint rules.
+	Accept acceptFunc
+
+	backendResult
+}
+
+// Execute starts every backend with a shared cancelable context and waits for the first success.
+func (e *RaceExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type raceResult struct {
+		name   string
+		output string
+		err    error
+	}
+	// Buffered so that losing backends can always deliver their result and exit
+	results := make(chan raceResult, len(e.Backends))
+	for _, backend := range e.Backends {
+		go func(backend NamedExecutor) {
+			output, err := backend.Executor.Execute(raceCtx, prompt)
+			if err == nil {
+				err = e.Accept.check(output)
+			}
+			results <- raceResult{name: backend.Name, output: output, err: err}
+		}(backend)
+	}
+
+	var failures []*BackendError
+	var rejected lintRejected
+	for range e.Backends {
+		result := <-results
+		if result.err == nil {
+			cancel()
+			e.record(result.name, failures)
+			return result.output, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		rejected.offer(result.name, result.output, result.err)
+		failures = append(failures, &BackendError{Name: result.name, Err: result.err})
+	}
+
+	if rejected.backend != "" {
+		e.record(rejected.backend, rejected.without(failures))
+		return rejected.output, nil
+	}
+	e.record("", failures)
+	return "", &ChainError{Failures: failures}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// funcExecutor adapts a function to the AIExecutor interface for tests.
+type funcExecutor func(ctx context.Context, prompt string) (string, error)
+
+func (f funcExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	return f(ctx, prompt)
+}
+
+func TestFallbackExecutor(t *testing.T) {
+	errRateLimited := errors.New("rate limited")
+	var calls []string
+	backend := func(name, output string, err error) NamedExecutor {
+		return NamedExecutor{Name: name, Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			calls = append(calls, name)
+			return output, err
+		})}
+	}
+
+	tests := []struct {
+		name         string
+		backends     []NamedExecutor
+		want         string
+		wantCalls    []string
+		wantBackend  string
+		wantFailures []string
+		wantError    bool
+	}{
+		{
+			name:        "first backend succeeds",
+			backends:    []NamedExecutor{backend("claude", "feat: a", nil), backend("gemini", "feat: b", nil)},
+			want:        "feat: a",
+			wantCalls:   []string{"claude"},
+			wantBackend: "claude",
+		},
+		{
+			name:         "falls back on error and empty output",
+			backends:     []NamedExecutor{backend("claude", "", errRateLimited), backend("gemini", "  \n", nil), backend("codex", "fix: c", nil)},
+			want:         "fix: c",
+			wantCalls:    []string{"claude", "gemini", "codex"},
+			wantBackend:  "codex",
+			wantFailures: []string{"claude: rate limited", "gemini: empty response"},
+		},
+		{
+			name:         "all backends fail",
+			backends:     []NamedExecutor{backend("claude", "", errRateLimited), backend("gemini", "", errRateLimited)},
+			wantCalls:    []string{"claude", "gemini"},
+			wantFailures: []string{"claude: rate limited", "gemini: rate limited"},
+			wantError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			executor := &FallbackExecutor{Backends: tt.backends}
+			result, err := executor.Execute(context.Background(), "prompt")
+
+			if tt.wantError {
+				var chainErr *ChainError
+				if !errors.As(err, &chainErr) {
+					t.Fatalf("Execute() error = %v, want *ChainError", err)
+				}
+				if !errors.Is(err, errRateLimited) {
+					t.Errorf("Execute() error should wrap the backend errors")
+				}
+				if !strings.Contains(err.Error(), "all backends failed: claude: rate limited; gemini: rate limited") {
+					t.Errorf("Execute() error = %v", err)
+				}
+			} else if err != nil || result != tt.want {
+				t.Errorf("Execute() = (%q, %v), want %q", result, err, tt.want)
+			}
+
+			if strings.Join(calls, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("called %v, want %v", calls, tt.wantCalls)
+			}
+
+			backendName, failures := executor.LastResult()
+			if backendName != tt.wantBackend {
+				t.Errorf("LastResult() backend = %q, want %q", backendName, tt.wantBackend)
+			}
+			var got []string
+			for _, failure := range failures {
+				got = append(got, failure.Error())
+			}
+			if strings.Join(got, "|") != strings.Join(tt.wantFailures, "|") {
+				t.Errorf("LastResult() failures = %v, want %v", got, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestFallbackExecutorStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	secondCalled := false
+	executor := &FallbackExecutor{Backends: []NamedExecutor{
+		{Name: "claude", Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			cancel()
+			return "", ctx.Err()
+		})},
+		{Name: "gemini", Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			secondCalled = true
+			return "feat: x", nil
+		})},
+	}}
+
+	if _, err := executor.Execute(ctx, "prompt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Execute() error = %v, want context.Canceled", err)
+	}
+	if secondCalled {
+		t.Error("Execute() should not try the next backend after cancellation")
+	}
+}
+
+func TestNewExecutorFallbackList(t *testing.T) {
+	executor, err := newExecutor("claude, gemini,codex", defaultConfig(), nil)
+	if err != nil {
+		t.Fatalf("newExecutor() unexpected error = %v", err)
+	}
+	fallback, ok := executor.(*FallbackExecutor)
+	if !ok {
+		t.Fatalf("newExecutor() returned %T, want *FallbackExecutor", executor)
+	}
+	var names []string
+	for _, backend := range fallback.Backends {
+		names = append(names, backend.Name)
+	}
+	if strings.Join(names, ",") != "claude,gemini,codex" {
+		t.Errorf("backends = %v, want [claude gemini codex]", names)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := newExecutor("invalid,anthropic", defaultConfig(), nil); err == nil || !strings.Contains(err.Error(), "all backends failed: invalid: invalid model specified: invalid; anthropic: ") {
+		t.Errorf("newExecutor() error = %v, want the errors of every backend", err)
+	}
+}
+
+func TestNewExecutorSkipsBrokenBackends(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	cfg := defaultConfig()
+	cfg.Commands = map[string]CommandSpec{"echo": {Command: "echo", Args: []string{"feat: from echo"}}}
+	executor, err := newExecutor("anthropic,echo", cfg, nil)
+	if err != nil {
+		t.Fatalf("newExecutor() unexpected error = %v", err)
+	}
+
+	output, err := executor.Execute(context.Background(), "prompt")
+	if err != nil || strings.TrimSpace(output) != "feat: from echo" {
+		t.Fatalf("Execute() = %q, %v, want the output of the next backend", output, err)
+	}
+	backend, failures := executor.(backendReporter).LastResult()
+	if backend != "echo" || len(failures) != 1 || failures[0].Name != "anthropic" || !strings.Contains(failures[0].Err.Error(), "ANTHROPIC_API_KEY") {
+		t.Errorf("LastResult() = %q, %v, want the construction error of anthropic", backend, failures)
+	}
+}
+
+func TestNewExecutorAcceptsLikeAList(t *testing.T) {
+	rules := defaultLintRules()
+	rules.HeaderMaxLength = 20
+	accept := func(output string) error { return acceptCommitMessage(output, &rules) }
+	cfg := defaultConfig()
+	cfg.Retry = RetryPolicy{}
+	cfg.Commands = map[string]CommandSpec{
+		"prose": {Command: "echo", Args: []string{"Here is what I changed."}},
+		"long":  {Command: "echo", Args: []string{"feat: a header that is far too long"}},
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{"prose", "", errNoCommitHeader},
+		{"long", "feat: a header that is far too long", nil},
+	}
+	for _, tt := range tests {
+		// A single model and a list of models judge the response the same way
+		for _, model := range []string{tt.name, tt.name + "," + tt.name} {
+			executor, err := newExecutor(model, cfg, accept)
+			if err != nil {
+				t.Fatalf("newExecutor(%q) unexpected error = %v", model, err)
+			}
+			got, err := executor.Execute(context.Background(), "prompt")
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("newExecutor(%q).Execute() = %q, %v, want %q, %v", model, got, err, tt.want, tt.wantErr)
+			}
+		}
+	}
+}
+
+func TestRaceExecutor(t *testing.T) {
+	slowCanceled := make(chan struct{})
+	executor := &RaceExecutor{Backends: []NamedExecutor{
+		{Name: "slow", Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			<-ctx.Done()
+			close(slowCanceled)
+			return "", ctx.Err()
+		})},
+		{Name: "broken", Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			return "", &ExecutorError{Kind: errExecutorFailed, Detail: "Execution error"}
+		})},
+		{Name: "fast", Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			return "前置き\n\nfeat: 高速な応答", nil
+		})},
+	}}
+
+	result, err := executor.Execute(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Execute() unexpected error = %v", err)
+	}
+	if result != "前置き\n\nfeat: 高速な応答" {
+		t.Errorf("Execute() = %q, want raw response of the fast backend", result)
+	}
+
+	<-slowCanceled // the losing backend must be canceled
+
+	backend, failures := executor.LastResult()
+	if backend != "fast" {
+		t.Errorf("LastResult() backend = %q, want fast", backend)
+	}
+	for _, failure := range failures {
+		if failure.Name != "broken" || !strings.Contains(failure.Err.Error(), "AI returned an error response") {
+			t.Errorf("unexpected failure %v", failure)
+		}
+	}
+}
+
+func TestRaceExecutorAllFail(t *testing.T) {
+	executor := &RaceExecutor{Backends: []NamedExecutor{
+		{Name: "a", Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			return "", errors.New("not logged in")
+		})},
+		{Name: "b", Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			return "", nil
+		})},
+	}}
+
+	_, err := executor.Execute(context.Background(), "prompt")
+	var chainErr *ChainError
+	if !errors.As(err, &chainErr) || len(chainErr.Failures) != 2 {
+		t.Fatalf("Execute() error = %v, want *ChainError with 2 failures", err)
+	}
+	if !errors.Is(err, errEmptyResponse) {
+		t.Errorf("Execute() error should wrap errEmptyResponse")
+	}
+}
+
+func TestRaceExecutorAccept(t *testing.T) {
+	rules := defaultLintRules()
+	reply := func(output string, delay time.Duration) AIExecutor {
+		return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			time.Sleep(delay)
+			return output, nil
+		})
+	}
+	longHeader := "feat: " + strings.Repeat("x", 120)
+
+	tests := []struct {
+		name        string
+		backends    []NamedExecutor
+		accept      acceptFunc
+		want        string
+		wantBackend string
+		wantFailed  []string
+	}{
+		{
+			name: "prose loses to a slower commit message",
+			backends: []NamedExecutor{
+				{Name: "prose", Executor: reply("Here is what I changed in the code.", 0)},
+				{Name: "message", Executor: reply("feat: add login", 20*time.Millisecond)},
+			},
+			want: "feat: add login", wantBackend: "message", wantFailed: []string{"prose"},
+		},
+		{
+			name: "lint errors lose to a slower valid message",
+			backends: []NamedExecutor{
+				{Name: "long", Executor: reply(longHeader, 0)},
+				{Name: "valid", Executor: reply("feat: add login", 20*time.Millisecond)},
+			},
+			want: "feat: add login", wantBackend: "valid", wantFailed: []string{"long"},
+		},
+		{
+			name: "lint errors are used when nothing does better",
+			backends: []NamedExecutor{
+				{Name: "prose", Executor: reply("Sorry, no.", 0)},
+				{Name: "long", Executor: reply(longHeader, 20*time.Millisecond)},
+			},
+			want: longHeader, wantBackend: "long", wantFailed: []string{"prose"},
+		},
+		{
+			name: "other prompts use their own check",
+			backends: []NamedExecutor{
+				{Name: "summary", Executor: reply("- summary of the change", 0)},
+			},
+			accept: acceptNonEmpty,
+			want:   "- summary of the change", wantBackend: "summary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accept := tt.accept
+			if accept == nil {
+				accept = func(output string) error { return acceptCommitMessage(output, &rules) }
+			}
+			executor := &RaceExecutor{Backends: tt.backends, Accept: accept}
+			got, err := executor.Execute(context.Background(), "prompt")
+			if err != nil || got != tt.want {
+				t.Fatalf("Execute() = %q, %v, want %q", got, err, tt.want)
+			}
+			backend, failures := executor.LastResult()
+			var failed []string
+			for _, failure := range failures {
+				failed = append(failed, failure.Name)
+			}
+			if backend != tt.wantBackend || strings.Join(failed, ",") != strings.Join(tt.wantFailed, ",") {
+				t.Errorf("LastResult() = %q, %v, want %q, %v", backend, failed, tt.wantBackend, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestNewExecutorStrategy(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Strategy = strategyRace
+	executor, err := newExecutor("claude,gemini", cfg, nil)
+	if err != nil {
+		t.Fatalf("newExecutor() unexpected error = %v", err)
+	}
+	if _, ok := executor.(*RaceExecutor); !ok {
+		t.Errorf("newExecutor() returned %T, want *RaceExecutor", executor)
+	}
+
+	cfg.Strategy = "random"
+	if _, err := newExecutor("claude,gemini", cfg, nil); err == nil || !strings.Contains(err.Error(), "invalid strategy") {
+		t.Errorf("newExecutor() error = %v, want invalid strategy error", err)
+	}
+}
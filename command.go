@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Prompt delivery modes for CommandSpec.
+const (
+	promptModeStdin = "stdin"
+	promptModeArg   = "arg"
+)
+
+// promptPlaceholder is replaced by the prompt in CommandSpec.Args when PromptMode is "arg".
+const promptPlaceholder = "{prompt}"
+
+// CommandSpec describes how to invoke an AI CLI and clean up its output.
+type CommandSpec struct {
+	Command string   `toml:"command"`
+	Args    []string `toml:"args"`
+	// PromptMode is "stdin" (default) or "arg". In "arg" mode the prompt replaces
+	// every "{prompt}" in Args, or is appended when Args has no placeholder.
+	PromptMode string `toml:"prompt_mode"`
+	// Filters are regular expressions; output lines matching any of them are dropped.
+	Filters []string `toml:"filters"`
+	// StartMarker is a regular expression; when set, only the lines after the
+	// last matching line are treated as the response.
+	StartMarker string `toml:"start_marker"`
+	// ErrorPatterns are regular expressions for the error banners the command prints
+	// while exiting successfully; a response line matching any of them is a failure.
+	ErrorPatterns []string `toml:"error_patterns"`
+	// ErrorsOnStdout classifies the filtered stdout of a failed run along with stderr,
+	// for CLIs that report failures there. It must stay off for CLIs that echo the
+	// prompt, or the diff would decide how a failure is handled.
+	ErrorsOnStdout bool `toml:"errors_on_stdout"`
+}
+
+// Validate checks that the spec can be executed.
+func (s CommandSpec) Validate() error {
+	if s.Command == "" {
+		return errors.New("command is required")
+	}
+	switch s.PromptMode {
+	case "", promptModeStdin, promptModeArg:
+	default:
+		return fmt.Errorf("invalid prompt_mode %q (expected %s or %s)", s.PromptMode, promptModeStdin, promptModeArg)
+	}
+	if _, err := s.compile(); err != nil {
+		return err
+	}
+	return nil
+}
+
+type compiledFilters struct {
+	filters       []*regexp.Regexp
+	startMarker   *regexp.Regexp
+	errorPatterns []*regexp.Regexp
+}
+
+func (s CommandSpec) compile() (*compiledFilters, error) {
+	compiled := &compiledFilters{}
+	for _, pattern := range s.Filters {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", pattern, err)
+		}
+		compiled.filters = append(compiled.filters, re)
+	}
+	if s.StartMarker != "" {
+		re, err := regexp.Compile(s.StartMarker)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start_marker %q: %w", s.StartMarker, err)
+		}
+		compiled.startMarker = re
+	}
+	for _, pattern := range s.ErrorPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error pattern %q: %w", pattern, err)
+		}
+		compiled.errorPatterns = append(compiled.errorPatterns, re)
+	}
+	return compiled, nil
+}
+
+// Built-in command specs for the supported AI CLIs.
+var (
+	claudeCommandSpec = CommandSpec{
+		Command: "claude",
+		Args:    []string{"-p"},
+		Filters: []string{`🤖 Generated with`, `Co-Authored-By: Claude`},
+		ErrorPatterns: []string{
+			`^Execution error$`, `^Invalid API key`, `^API Error: `, `^Credit balance is too low`,
+			`^Claude AI usage limit reached`, `^Prompt is too long$`,
+		},
+		ErrorsOnStdout: true,
+	}
+	geminiCommandSpec = CommandSpec{
+		Command:       "gemini",
+		Args:          []string{"-p"},
+		Filters:       []string{`Loaded cached credentials\.`},
+		ErrorPatterns: []string{`^Error when talking to Gemini API`, `^\[API Error: `, `^Quota exceeded`},
+	}
+	// codex exec outputs log lines (e.g. "[2026-02-25T00:25:46] codex", "[...] tokens used: N")
+	// along with echoed prompt content. The last "[...] codex" line marks the start of the AI response.
+	codexCommandSpec = CommandSpec{
+		Command:       "codex",
+		Args:          []string{"exec", promptPlaceholder},
+		PromptMode:    promptModeArg,
+		Filters:       []string{`^\s*\[.*\] tokens used:`},
+		StartMarker:   `^\s*\[.*\] codex`,
+		ErrorPatterns: []string{`^(\[.*\] )?ERROR: `, `^(\[.*\] )?stream error: `},
+	}
+)
+
+// CommandExecutor implements AIExecutor for any CLI described by a CommandSpec.
+type CommandExecutor struct {
+	Name string
+	Spec CommandSpec
+}
+
+// Execute runs the configured command with the given prompt and returns the filtered output.
+func (e *CommandExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	compiled, err := e.Spec.compile()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", e.Name, err)
+	}
+
+	args := e.Spec.Args
+	if e.Spec.PromptMode == promptModeArg {
+		args = make([]string, 0, len(e.Spec.Args)+1)
+		replaced := false
+		for _, arg := range e.Spec.Args {
+			if strings.Contains(arg, promptPlaceholder) {
+				arg = strings.ReplaceAll(arg, promptPlaceholder, prompt)
+				replaced = true
+			}
+			args = append(args, arg)
+		}
+		if !replaced {
+			args = append(args, prompt)
+		}
+	}
+
+	// #nosec G204 - the command comes from the built-in specs or the user's own configuration
+	cmd := exec.CommandContext(ctx, e.Spec.Command, args...)
+	if e.Spec.PromptMode != promptModeArg {
+		cmd.Stdin = strings.NewReader(prompt)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// Stdout may echo the prompt, so it is only classified for CLIs that report
+			// failures there
+			report := string(exitErr.Stderr)
+			if e.Spec.ErrorsOnStdout {
+				report = compiled.apply(string(output)) + "\n" + report
+			}
+			return "", classifyFailure(report, fmt.Errorf("%s execution failed: %w: %s", e.Name, err, string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to run %s command: %w", e.Name, err)
+	}
+
+	response := compiled.apply(string(output))
+	if err := classifyOutput(response, compiled.errorPatterns); err != nil {
+		return "", fmt.Errorf("%s: %w", e.Name, err)
+	}
+	return response, nil
+}
+
+// apply extracts the response from raw command output.
+func (c *compiledFilters) apply(output string) string {
+	lines := strings.Split(output, "\n")
+
+	startIndex := 0
+	if c.startMarker != nil {
+		for i, line := range lines {
+			if c.startMarker.MatchString(line) {
+				startIndex = i + 1
+			}
+		}
+	}
+
+	var filteredLines []string
+	for _, line := range lines[startIndex:] {
+		if !c.matchesFilter(line) {
+			filteredLines = append(filteredLines, line)
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(filteredLines, "\n"))
+}
+
+func (c *compiledFilters) matchesFilter(line string) bool {
+	for _, re := range c.filters {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandExecutor(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          CommandSpec
+		want          string
+		wantError     bool
+		errorContains string
+	}{
+		{
+			name: "prompt via stdin",
+			spec: CommandSpec{Command: "sh", Args: []string{"-c", "cat"}},
+			want: "test prompt",
+		},
+		{
+			name: "prompt via placeholder argument",
+			spec: CommandSpec{Command: "sh", Args: []string{"-c", `echo "got: $1"`, "sh", "{prompt}"}, PromptMode: promptModeArg},
+			want: "got: test prompt",
+		},
+		{
+			name: "prompt appended when no placeholder",
+			spec: CommandSpec{Command: "echo", Args: []string{"prefix"}, PromptMode: promptModeArg},
+			want: "prefix test prompt",
+		},
+		{
+			name: "filters and start marker",
+			spec: CommandSpec{
+				Command:     "printf",
+				Args:        []string{`[t1] codex\nold\n[t2] codex\nfeat: new\n[t3] tokens used: 10\n`},
+				PromptMode:  promptModeArg,
+				Filters:     []string{`^\[.*\] tokens used:`},
+				StartMarker: `^\[.*\] codex`,
+			},
+			want: "feat: new",
+		},
+		{
+			name:          "non-zero exit includes stderr",
+			spec:          CommandSpec{Command: "sh", Args: []string{"-c", "echo boom >&2; exit 3"}},
+			wantError:     true,
+			errorContains: "custom execution failed: exit status 3: boom",
+		},
+		{
+			name:          "missing command",
+			spec:          CommandSpec{Command: "gcauto-no-such-command"},
+			wantError:     true,
+			errorContains: "failed to run custom command",
+		},
+		{
+			name:          "invalid filter",
+			spec:          CommandSpec{Command: "cat", Filters: []string{"("}},
+			wantError:     true,
+			errorContains: "invalid filter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &CommandExecutor{Name: "custom", Spec: tt.spec}
+			result, err := executor.Execute(context.Background(), "test prompt")
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("Execute() expected error but got none")
+				}
+				if !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Execute() error = %v, want error containing %s", err, tt.errorContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Execute() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinCommandSpecsFilterOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   CommandSpec
+		output string
+		want   string
+	}{
+		{
+			name:   "claude",
+			spec:   claudeCommandSpec,
+			output: "feat: 追加\n\n🤖 Generated with [Claude Code]\nCo-Authored-By: Claude <noreply@anthropic.com>\n",
+			want:   "feat: 追加",
+		},
+		{
+			name:   "gemini",
+			spec:   geminiCommandSpec,
+			output: "Loaded cached credentials.\nfix: 修正\n",
+			want:   "fix: 修正",
+		},
+		{
+			name:   "codex",
+			spec:   codexCommandSpec,
+			output: "[2026-02-25T00:25:40] User instructions:\nprompt\n[2026-02-25T00:25:46] codex\nfeat: 追加\n[2026-02-25T00:25:47] tokens used: 1234\n",
+			want:   "feat: 追加",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := tt.spec.compile()
+			if err != nil {
+				t.Fatalf("compile() unexpected error = %v", err)
+			}
+			if got := compiled.apply(tt.output); got != tt.want {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewExecutorConfiguredCommand(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	if err := os.MkdirAll(filepath.Join(configDir, "gcauto"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := `[commands.llm]
+command = "sh"
+args = ["-c", "echo 'Thinking...'; echo \"feat: $1\"", "sh", "{prompt}"]
+prompt_mode = "arg"
+filters = ['^Thinking\.\.\.$']
+
+[commands.broken]
+prompt_mode = "pipe"
+`
+	if err := os.WriteFile(filepath.Join(configDir, "gcauto", "config.toml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error = %v", err)
+	}
+
+	executor, err := newExecutor("llm", cfg, nil)
+	if err != nil {
+		t.Fatalf("newExecutor() unexpected error = %v", err)
+	}
+	result, err := executor.Execute(context.Background(), "from config")
+	if err != nil {
+		t.Fatalf("Execute() unexpected error = %v", err)
+	}
+	if result != "feat: from config" {
+		t.Errorf("Execute() = %q, want %q", result, "feat: from config")
+	}
+
+	if _, err := newExecutor("broken", cfg, nil); err == nil || !strings.Contains(err.Error(), "command is required") {
+		t.Errorf("newExecutor() error = %v, want invalid command error", err)
+	}
+}
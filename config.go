@@ -0,0 +1,514 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// repoConfigFile is the per-repository config file name, looked up at the repository root.
+const repoConfigFile = ".gcauto.toml"
+
+// sourceDefault marks configuration values that were not set by any layer.
+const sourceDefault = "default"
+
+// Config holds the effective gcauto configuration. It is assembled from layers in
+// increasing precedence: built-in defaults, the global config file, the repository
+// config file, GCAUTO_* environment variables and finally command-line flags.
+type Config struct {
+	// Models is the model list, tried in order (see Strategy).
+	Models []string `toml:"models"`
+	// Strategy combines multiple models: "fallback" (default) or "race".
+	Strategy string `toml:"strategy"`
+	// Retry controls retries and per-attempt timeouts of each backend.
+	Retry RetryPolicy `toml:"retry"`
+	// Anthropic configures the Messages API executor.
+	Anthropic AnthropicConfig `toml:"anthropic"`
+	// OpenAI configures the OpenAI-compatible chat completions executor.
+	OpenAI OpenAIConfig `toml:"openai"`
+	// Commands declares additional CLI executors selectable by name with -model.
+	Commands map[string]CommandSpec `toml:"commands"`
+	// Prompt configures the prompt sent to the AI.
+	Prompt PromptConfig `toml:"prompt"`
+	// Examples configures few-shot examples taken from the commit history.
+	Examples ExamplesConfig `toml:"examples"`
+	// Scope configures how the commit scope is derived from the staged paths.
+	Scope ScopeConfig `toml:"scope"`
+	// Tickets are the rules that copy ticket IDs from the branch name into the message.
+	Tickets []TicketRule `toml:"tickets"`
+	// Secrets configures the redaction of secrets in the diff sent to the AI.
+	Secrets SecretsConfig `toml:"secrets"`
+	// Lint configures the rules generated messages are checked against.
+	Lint LintConfig `toml:"lint"`
+	// Diff configures how the staged diff is passed to the AI.
+	Diff DiffConfig `toml:"diff"`
+	// Behavior holds toggles for the commit flow.
+	Behavior BehaviorConfig `toml:"behavior"`
+
+	// sources maps each key set by a layer to a description of that layer.
+	sources map[string]string
+	// warnings describe the settings of the repository config file that were ignored.
+	warnings []string
+}
+
+// AnthropicConfig holds the settings of AnthropicExecutor. The API key is only read
+// from ANTHROPIC_API_KEY so that it never ends up in a config file.
+type AnthropicConfig struct {
+	Model     string `toml:"model"`
+	MaxTokens int    `toml:"max_tokens"`
+	BaseURL   string `toml:"base_url"`
+}
+
+// OpenAIConfig holds the settings of OpenAIExecutor. The API key is only read from OPENAI_API_KEY.
+type OpenAIConfig struct {
+	BaseURL    string `toml:"base_url"`
+	Model      string `toml:"model"`
+	AuthHeader string `toml:"auth_header"`
+}
+
+// PromptConfig holds options for the prompt.
+type PromptConfig struct {
+	// Language is the output language code such as "en" or "ja", or "auto" to follow
+	// the recent commit history.
+	Language string `toml:"language"`
+	// Instructions are appended to the prompt, e.g. team-specific conventions.
+	Instructions string `toml:"instructions"`
+	// Template is a text/template file replacing the built-in prompt. Relative paths
+	// are resolved against the repository root.
+	Template string `toml:"template"`
+	// Types are the allowed commit types.
+	Types []string `toml:"types"`
+	// Scopes are the allowed scopes; empty leaves the scope to the AI.
+	Scopes []string `toml:"scopes"`
+}
+
+// ExamplesConfig controls the past commit messages shown to the AI as style examples.
+type ExamplesConfig struct {
+	// Count is the number of examples; zero disables them.
+	Count int `toml:"count"`
+	// SamePaths prefers commits that touched the staged files.
+	SamePaths bool `toml:"same_paths"`
+	// Scan is the number of recent commits the examples are chosen from.
+	Scan int `toml:"scan"`
+}
+
+// DiffConfig holds limits for the diff sent to the AI.
+type DiffConfig struct {
+	// MaxTokens is the diff budget in estimated tokens for every backend; zero uses
+	// the budget of the backend from Budgets.
+	MaxTokens int `toml:"max_tokens"`
+	// Budgets are the diff budgets in estimated tokens by backend name.
+	Budgets map[string]int `toml:"budgets"`
+	// MaxSize is the legacy diff budget in bytes, used when MaxTokens is zero.
+	MaxSize int `toml:"max_size"`
+	// Mode handles diffs over the budget: "truncate" (default) leaves out the least
+	// meaningful files and hunks, while "summarize" has each chunk summarized and
+	// writes the message from the summaries.
+	Mode string `toml:"mode"`
+	// ChunkSize is the maximum number of diff bytes per summarized chunk.
+	ChunkSize int `toml:"chunk_size"`
+	// Parallel is the maximum number of chunks summarized at the same time.
+	Parallel int `toml:"parallel"`
+}
+
+// BehaviorConfig holds toggles for the commit flow.
+type BehaviorConfig struct {
+	// AutoConfirm commits without prompting, like -y.
+	AutoConfirm bool `toml:"auto_confirm"`
+	// PreCommit runs pre-commit hooks before generating the message.
+	PreCommit bool `toml:"pre_commit"`
+	// Candidates is the number of messages to generate, like -n.
+	Candidates int `toml:"candidates"`
+	// AmendContext shows the AI the current message of the commit when amending.
+	AmendContext bool `toml:"amend_context"`
+}
+
+// envAliases lists well-known environment variables accepted in addition to the
+// GCAUTO_* name of a key. The GCAUTO_* name wins when both are set.
+var envAliases = map[string]string{
+	"anthropic.base_url": "ANTHROPIC_BASE_URL",
+	"openai.base_url":    "OPENAI_BASE_URL",
+}
+
+// defaultConfig returns the built-in configuration.
+func defaultConfig() *Config {
+	return &Config{
+		Models:   []string{"codex"},
+		Strategy: strategyFallback,
+		Retry:    defaultRetryPolicy(),
+		Anthropic: AnthropicConfig{
+			Model:     defaultAnthropicModel,
+			MaxTokens: defaultAnthropicMaxTokens,
+			BaseURL:   defaultAnthropicBaseURL,
+		},
+		OpenAI: OpenAIConfig{
+			BaseURL:    defaultOpenAIBaseURL,
+			Model:      defaultOpenAIModel,
+			AuthHeader: defaultOpenAIAuthHeader,
+		},
+		Commands: map[string]CommandSpec{},
+		Prompt: PromptConfig{
+			Language: defaultLanguage,
+			Types:    append([]string(nil), conventionalTypes...),
+		},
+		Examples: ExamplesConfig{
+			Scan: defaultExamplesScan,
+		},
+		Secrets: SecretsConfig{
+			Policy:       secretPolicyWarn,
+			MinEntropy:   defaultSecretMinEntropy,
+			BlockCommits: true,
+		},
+		Lint: LintConfig{
+			Enabled:        true,
+			Commitlint:     lintCommitlintAuto,
+			RepairAttempts: defaultRepairAttempts,
+			Rules:          defaultLintRules(),
+		},
+		Diff: DiffConfig{
+			Budgets:   maps.Clone(defaultDiffBudgets),
+			Mode:      diffModeTruncate,
+			ChunkSize: defaultChunkSize,
+			Parallel:  defaultParallelism,
+		},
+		Behavior: BehaviorConfig{
+			PreCommit:    true,
+			Candidates:   1,
+			AmendContext: true,
+		},
+		sources: map[string]string{},
+	}
+}
+
+// globalConfigDir returns $XDG_CONFIG_HOME/gcauto, falling back to ~/.config/gcauto.
+func globalConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gcauto")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcauto")
+}
+
+// globalConfigPath returns the path of the global config file, or "" if the home directory is unknown.
+func globalConfigPath() string {
+	dir := globalConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.toml")
+}
+
+// gitRepoRoot returns the top-level directory of the current git repository.
+func gitRepoRoot(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// loadConfig merges the defaults, the global config file, the repository config file
+// and the environment. Missing files are skipped. Flags are applied by the caller.
+func loadConfig(ctx context.Context) (*Config, error) {
+	cfg := defaultConfig()
+	if path := globalConfigPath(); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if root, err := gitRepoRoot(ctx); err == nil {
+		if err := cfg.loadRepoFile(filepath.Join(root, repoConfigFile)); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile merges the TOML file at path into c. A missing file is not an error.
+func (c *Config) loadFile(path string) error {
+	return c.mergeFile(path, false)
+}
+
+// loadRepoFile merges the repository config file at path into c like loadFile, but
+// ignores the settings a repository may not choose; see dropRepoRestricted.
+func (c *Config) loadRepoFile(path string) error {
+	return c.mergeFile(path, true)
+}
+
+func (c *Config) mergeFile(path string, restricted bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	table, err := parseTOML(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if restricted {
+		for _, key := range dropRepoRestricted(table) {
+			c.warnings = append(c.warnings, fmt.Sprintf("%s: ignoring %s, which only the global config, environment variables and flags may set", path, key))
+		}
+	}
+	err = decodeTOML(table, c, func(key string) {
+		c.setSource(key, path)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// dropRepoRestricted removes from the table of a repository config file the settings
+// that decide which programs run and where the API keys and the diff are sent:
+// commands, the API base URLs and models with a base URL. Paths of files read into
+// the prompt or the lint rules must stay inside the repository. Otherwise cloning a
+// repository and running gcauto in it would run whatever the repository chooses, or
+// send any file of the user to the AI. It returns the keys it removed.
+func dropRepoRestricted(table map[string]any) []string {
+	var dropped []string
+	if _, ok := table["commands"]; ok {
+		delete(table, "commands")
+		dropped = append(dropped, "commands")
+	}
+	for _, section := range []string{"anthropic", "openai"} {
+		if settings, ok := table[section].(map[string]any); ok {
+			if _, ok := settings["base_url"]; ok {
+				delete(settings, "base_url")
+				dropped = append(dropped, section+".base_url")
+			}
+		}
+	}
+	if models, ok := table["models"].([]any); ok {
+		kept := make([]any, 0, len(models))
+		for _, model := range models {
+			if spec, isString := model.(string); isString {
+				if _, baseURL, _ := parseModelSpec(spec); baseURL != "" {
+					dropped = append(dropped, fmt.Sprintf("model %q with a base URL", spec))
+					continue
+				}
+			}
+			kept = append(kept, model)
+		}
+		if len(kept) == 0 {
+			delete(table, "models")
+		} else {
+			table["models"] = kept
+		}
+	}
+	for _, setting := range []struct{ section, key string }{{"prompt", "template"}, {"lint", "commitlint"}} {
+		settings, ok := table[setting.section].(map[string]any)
+		if !ok {
+			continue
+		}
+		if path, isString := settings[setting.key].(string); isString && path != "" && !filepath.IsLocal(path) {
+			delete(settings, setting.key)
+			dropped = append(dropped, fmt.Sprintf("%s.%s %q outside the repository", setting.section, setting.key, path))
+		}
+	}
+	return dropped
+}
+
+// applyEnv overrides scalar and list settings from GCAUTO_<SECTION>_<KEY> variables,
+// e.g. GCAUTO_RETRY_TIMEOUT for retry.timeout. Lists are comma-separated.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var firstErr error
+	walkConfig(reflect.ValueOf(c).Elem(), "", false, func(key string, field reflect.Value) {
+		if firstErr != nil {
+			return
+		}
+		name := "GCAUTO_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			if alias, hasAlias := envAliases[key]; hasAlias {
+				name = alias
+				value, ok = lookup(alias)
+			}
+		}
+		if !ok || value == "" {
+			return
+		}
+		if err := setConfigString(field, value); err != nil {
+			firstErr = fmt.Errorf("invalid %s: %w", name, err)
+			return
+		}
+		c.setSource(key, "env "+name)
+	})
+	return firstErr
+}
+
+// setSource records where the value of key came from.
+func (c *Config) setSource(key, source string) {
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	c.sources[key] = source
+}
+
+// source returns where the value of key came from.
+func (c *Config) source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return sourceDefault
+}
+
+// walkConfig calls fn for every leaf setting below v, identified by its dotted key.
+// Entries of map sections such as commands are only visited when withMaps is set,
+// since they cannot be assigned through reflection.
+func walkConfig(v reflect.Value, prefix string, withMaps bool, fn func(key string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			walkConfig(field, key+".", withMaps, fn)
+		case field.Kind() == reflect.Map:
+			if !withMaps {
+				continue
+			}
+			names := make([]string, 0, field.Len())
+			for _, k := range field.MapKeys() {
+				names = append(names, k.String())
+			}
+			sort.Strings(names)
+			for _, n := range names {
+				entry := field.MapIndex(reflect.ValueOf(n))
+				if entry.Kind() == reflect.Struct {
+					walkConfig(entry, key+"."+n+".", withMaps, fn)
+				} else {
+					fn(key+"."+n, entry)
+				}
+			}
+		default:
+			fn(key, field)
+		}
+	}
+}
+
+// setConfigString parses value according to the type of field and stores it.
+func setConfigString(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return errors.New("cannot be set from the environment")
+		}
+		field.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return errors.New("cannot be set from the environment")
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// promptOptions returns the prompt settings of c. The language, template and
+// repository context are filled in by newPromptOptions.
+func (c *Config) promptOptions() promptOptions {
+	return promptOptions{
+		MaxDiffTokens: c.Diff.tokenBudget(c.Models),
+		Instructions:  c.Prompt.Instructions,
+		Types:         c.Prompt.Types,
+		Scopes:        c.Prompt.Scopes,
+	}
+}
+
+// writeTo prints every effective setting with the layer it came from.
+func (c *Config) writeTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	walkConfig(reflect.ValueOf(c).Elem(), "", true, func(key string, field reflect.Value) {
+		_, _ = fmt.Fprintf(tw, "%s = %s\t# %s\n", key, formatConfigValue(field), c.source(key))
+	})
+	return tw.Flush()
+}
+
+// formatConfigValue renders a setting in TOML syntax.
+func formatConfigValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return strconv.Quote(time.Duration(v.Int()).String())
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Slice:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, formatConfigValue(v.Index(i)))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Struct:
+		var fields []string
+		walkConfig(v, "", false, func(key string, field reflect.Value) {
+			if !field.IsZero() {
+				fields = append(fields, key+" = "+formatConfigValue(field))
+			}
+		})
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
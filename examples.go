@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// defaultExamplesScan is the number of recent commits examples are chosen from.
+const defaultExamplesScan = 50
+
+// maxExampleLines caps the length of each example so that long bodies do not crowd out the diff.
+const maxExampleLines = 12
+
+// maxExamplePaths caps the number of pathspecs passed to git log for same_paths.
+const maxExamplePaths = 100
+
+// skippedExamplePrefixes mark commits whose message does not reflect the repository's style.
+var skippedExamplePrefixes = []string{"fixup!", "squash!", "amend!", "Merge ", "WIP"}
+
+// loadExamples returns up to settings.Count past commit messages to show the AI as
+// style examples. With SamePaths, commits touching the staged files are preferred and
+// the remaining slots are filled from the recent history. Without history it returns nil.
+func loadExamples(ctx context.Context, settings ExamplesConfig, fileList string) []string {
+	if settings.Count <= 0 {
+		return nil
+	}
+	scan := max(settings.Scan, settings.Count)
+
+	var related []string
+	if settings.SamePaths && fileList != "" {
+		var paths []string
+		for _, path := range strings.Split(fileList, "\n") {
+			if len(paths) == maxExamplePaths {
+				break
+			}
+			// The file list is relative to the repository root, not the working directory
+			paths = append(paths, ":(top)"+path)
+		}
+		related, _ = getRecentCommitMessages(ctx, scan, paths...)
+	}
+	recent, err := getRecentCommitMessages(ctx, scan)
+	if err != nil {
+		// A repository without commits has no examples to offer
+		recent = nil
+	}
+	return selectExamples(settings.Count, related, recent)
+}
+
+// selectExamples picks up to n representative messages, exhausting each source before
+// the next. Within a source it takes the newest message of each commit type in turn so
+// that the examples cover different types; duplicates and fixup-style commits are skipped.
+func selectExamples(n int, sources ...[]string) []string {
+	var selected []string
+	seen := map[string]bool{}
+	for _, source := range sources {
+		if len(selected) >= n {
+			break
+		}
+
+		// Group the usable messages by type, keeping the groups in order of first appearance
+		var order []string
+		groups := map[string][]string{}
+		for _, message := range source {
+			message = trimExample(message)
+			subject, _, _ := strings.Cut(message, "\n")
+			if subject == "" || seen[subject] || hasSkippedPrefix(subject) {
+				continue
+			}
+			seen[subject] = true
+			typ := subjectType(subject)
+			if _, ok := groups[typ]; !ok {
+				order = append(order, typ)
+			}
+			groups[typ] = append(groups[typ], message)
+		}
+
+		for len(selected) < n && len(order) > 0 {
+			remaining := order[:0]
+			for _, typ := range order {
+				if len(selected) >= n {
+					break
+				}
+				selected = append(selected, groups[typ][0])
+				if groups[typ] = groups[typ][1:]; len(groups[typ]) > 0 {
+					remaining = append(remaining, typ)
+				}
+			}
+			order = remaining
+		}
+	}
+	return selected
+}
+
+// trimExample removes surrounding blank lines and cuts the message to maxExampleLines.
+func trimExample(message string) string {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	if len(lines) > maxExampleLines {
+		lines = lines[:maxExampleLines]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func hasSkippedPrefix(subject string) bool {
+	for _, prefix := range skippedExamplePrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// subjectType returns the conventional commit type of a subject such as
+// "feat(api)!: add x", or "" when the subject does not start with one.
+func subjectType(subject string) string {
+	end := strings.IndexAny(subject, "(!:")
+	if end <= 0 {
+		return ""
+	}
+	typ := subject[:end]
+	for _, r := range typ {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return ""
+		}
+	}
+	return strings.ToLower(typ)
+}
+
+// _getRecentCommitMessages returns the full messages of the last n non-merge commits,
+// limited to commits touching paths when any are given.
+func _getRecentCommitMessages(ctx context.Context, n int, paths ...string) ([]string, error) {
+	args := []string{"log", "--no-merges", fmt.Sprintf("-n%d", n), "--format=%B%x00"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	// #nosec G204 - paths come from git's own list of staged files
+	cmd := exec.CommandContext(ctx, "git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, message := range strings.Split(string(output), "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+var getRecentCommitMessages = _getRecentCommitMessages
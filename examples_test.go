@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectExamples(t *testing.T) {
+	recent := []string{
+		"feat(api): add users endpoint",
+		"feat(api): add groups endpoint",
+		"fixup! feat(api): add users endpoint",
+		"Merge branch 'main'",
+		"fix(cli): handle empty input\n\nbody line",
+		"feat(api): add users endpoint",
+		"docs: update README",
+		"chore: bump deps",
+	}
+	tests := []struct {
+		name    string
+		n       int
+		sources [][]string
+		want    []string
+	}{
+		{
+			name:    "one of each type first",
+			n:       4,
+			sources: [][]string{recent},
+			want: []string{
+				"feat(api): add users endpoint",
+				"fix(cli): handle empty input\n\nbody line",
+				"docs: update README",
+				"chore: bump deps",
+			},
+		},
+		{
+			name:    "second round after all types",
+			n:       5,
+			sources: [][]string{recent},
+			want: []string{
+				"feat(api): add users endpoint",
+				"fix(cli): handle empty input\n\nbody line",
+				"docs: update README",
+				"chore: bump deps",
+				"feat(api): add groups endpoint",
+			},
+		},
+		{
+			name:    "earlier sources win",
+			n:       2,
+			sources: [][]string{{"docs: update README"}, recent},
+			want:    []string{"docs: update README", "feat(api): add users endpoint"},
+		},
+		{
+			name:    "fewer messages than requested",
+			n:       3,
+			sources: [][]string{{"Update README", "WIP"}},
+			want:    []string{"Update README"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectExamples(tt.n, tt.sources...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectExamples() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := strings.Repeat("line\n", maxExampleLines+5)
+	got := selectExamples(1, []string{"feat: long\n" + long})
+	if lines := strings.Count(got[0], "\n") + 1; lines != maxExampleLines {
+		t.Errorf("example has %d lines, want %d", lines, maxExampleLines)
+	}
+}
+
+func TestLoadExamples(t *testing.T) {
+	originalGet := getRecentCommitMessages
+	defer func() { getRecentCommitMessages = originalGet }()
+
+	var gotPaths [][]string
+	getRecentCommitMessages = func(ctx context.Context, n int, paths ...string) ([]string, error) {
+		if n != 50 {
+			t.Errorf("scan = %d, want 50", n)
+		}
+		gotPaths = append(gotPaths, paths)
+		if len(paths) > 0 {
+			return []string{"fix(api): related change"}, nil
+		}
+		return []string{"feat(cli): recent change", "docs: recent docs"}, nil
+	}
+
+	if got := loadExamples(context.Background(), ExamplesConfig{Count: 0, Scan: 50}, "api.go"); got != nil {
+		t.Errorf("loadExamples() with count 0 = %q, want nil", got)
+	}
+	if len(gotPaths) != 0 {
+		t.Errorf("loadExamples() with count 0 should not read the history")
+	}
+
+	got := loadExamples(context.Background(), ExamplesConfig{Count: 2, Scan: 50, SamePaths: true}, "api.go\ndocs/a.md")
+	want := []string{"fix(api): related change", "feat(cli): recent change"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadExamples() = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(gotPaths[0], []string{":(top)api.go", ":(top)docs/a.md"}) {
+		t.Errorf("paths = %q, want staged files relative to the top", gotPaths[0])
+	}
+
+	getRecentCommitMessages = func(ctx context.Context, n int, paths ...string) ([]string, error) {
+		return nil, errors.New("does not have any commits yet")
+	}
+	if got := loadExamples(context.Background(), ExamplesConfig{Count: 2, Scan: 50}, "api.go"); len(got) != 0 {
+		t.Errorf("loadExamples() without history = %q, want none", got)
+	}
+}
+
+func TestBuildCommitPromptExamples(t *testing.T) {
+	prompt, err := buildCommitPrompt("diff", "a.go", "stat", promptOptions{Language: "en", Examples: []string{"feat(api): ✨ add users [PROJ-1]"}})
+	if err != nil {
+		t.Fatalf("buildCommitPrompt() unexpected error = %v", err)
+	}
+	if !strings.Contains(prompt, "Past commit messages of this repository") || !strings.Contains(prompt, "---\nfeat(api): ✨ add users [PROJ-1]\n---") {
+		t.Errorf("prompt should contain the examples, got:\n%s", prompt)
+	}
+
+	prompt, err = buildCommitPrompt("diff", "a.go", "stat", promptOptions{Language: "ja"})
+	if err != nil {
+		t.Fatalf("buildCommitPrompt() unexpected error = %v", err)
+	}
+	if strings.Contains(prompt, "過去のコミットメッセージ") {
+		t.Errorf("prompt should not mention examples when there are none")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Kinds of ExecutorError, matched with errors.Is.
+var (
+	errAuth           = errors.New("authentication failed")
+	errRateLimited    = errors.New("rate limited")
+	errRefused        = errors.New("request refused by the model")
+	errExecutorFailed = errors.New("executor failed")
+)
+
+var (
+	// rateLimitPattern matches the rate-limit and overload messages printed by AI CLIs and APIs.
+	rateLimitPattern = regexp.MustCompile(`(?i)rate.?limit|too many requests|\b429\b|overloaded|resource.?exhausted|temporarily unavailable`)
+	// authPattern matches the login and API key errors printed by AI CLIs and APIs.
+	authPattern = regexp.MustCompile(`(?i)not logged in|please (run )?/?login|invalid (api[ _-]?key|x-api-key|token|credentials)|api[ _-]?key (is )?(missing|not set|invalid)|unauthori[sz]ed|authentication|\b401\b`)
+	// refusalPattern matches the opening of a response in which the model declines the request.
+	refusalPattern = regexp.MustCompile(`(?i)^(I'm sorry|I am sorry|sorry, (but )?I (can|won|am)|I can(no|')t (help|assist|comply|do that)|I (won't|will not|am unable to|'m unable to) (help|assist|comply)|申し訳(ありません|ございません)が)`)
+)
+
+// ExecutorError is an executor failure, classified by Kind so that callers can
+// tell a login problem from a rate limit or a refusal.
+type ExecutorError struct {
+	// Kind is one of errAuth, errRateLimited, errRefused or errExecutorFailed.
+	Kind error
+	// Detail is the part of the output that reported the failure.
+	Detail string
+	// Err is the underlying error, if the executor returned one.
+	Err error
+}
+
+func (e *ExecutorError) Error() string {
+	message := e.Detail
+	switch {
+	case e.Err != nil:
+		message = e.Err.Error()
+	case e.Kind == errExecutorFailed:
+		return "AI returned an error response: " + e.Detail
+	}
+	if e.Kind == errExecutorFailed {
+		return message
+	}
+	return e.Kind.Error() + ": " + message
+}
+
+// Unwrap exposes both the kind and the underlying error to errors.Is and errors.As.
+func (e *ExecutorError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// classifyFailure wraps err, the failure of a run whose output was output, in an
+// ExecutorError of the kind the output suggests.
+func classifyFailure(output string, err error) *ExecutorError {
+	kind := errExecutorFailed
+	switch {
+	case rateLimitPattern.MatchString(output):
+		kind = errRateLimited
+	case authPattern.MatchString(output):
+		kind = errAuth
+	}
+	return &ExecutorError{Kind: kind, Detail: firstLine(output), Err: err}
+}
+
+// classifyStatus wraps an API error in an ExecutorError of the kind its status code
+// and body indicate.
+func classifyStatus(statusErr *httpStatusError, err error) *ExecutorError {
+	switch statusErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &ExecutorError{Kind: errAuth, Detail: statusErr.Message, Err: err}
+	case http.StatusTooManyRequests:
+		return &ExecutorError{Kind: errRateLimited, Detail: statusErr.Message, Err: err}
+	}
+	return classifyFailure(statusErr.Message+"\n"+statusErr.Body, err)
+}
+
+// classifyOutput checks the output of a successful run. It returns an ExecutorError
+// when a line matches one of the executor's error banners, or when the response
+// declines the request instead of containing a commit message.
+func classifyOutput(output string, banners []*regexp.Regexp) error {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		for _, banner := range banners {
+			if banner.MatchString(line) {
+				return classifyFailure(line, nil)
+			}
+		}
+	}
+
+	first := firstLine(output)
+	if refusalPattern.MatchString(first) && !hasConventionalHeader(output) {
+		return &ExecutorError{Kind: errRefused, Detail: first}
+	}
+	return nil
+}
+
+// hasConventionalHeader reports whether any line of output is a conventional commit subject.
+func hasConventionalHeader(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if conventionalHeaderPattern.MatchString(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine returns the first non-empty line of s, trimmed.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// printFailureHints suggests what to do about a generation failure.
+func printFailureHints(err error) {
+	var hints []string
+	switch {
+	case errors.Is(err, errAuth):
+		hints = []string{"Log in to the AI CLI again or check the API key"}
+	case errors.Is(err, errRateLimited):
+		hints = []string{"Wait a moment and try again", "Use another model with --model"}
+	case errors.Is(err, errRefused):
+		hints = []string{"The model declined to write the message; try another model with --model"}
+	case errors.Is(err, errExecutorFailed):
+		hints = []string{
+			"The diff might be too large",
+			"The AI CLI might not be properly configured",
+			"Try staging fewer files or use --model gemini/codex",
+		}
+	default:
+		return
+	}
+	fmt.Println("\nPossible causes:")
+	for _, hint := range hints {
+		fmt.Printf("  - %s\n", hint)
+	}
+}
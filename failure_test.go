@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestClassifyOutput(t *testing.T) {
+	banners := []*regexp.Regexp{regexp.MustCompile(`^Execution error$`), regexp.MustCompile(`^Invalid API key`)}
+
+	tests := []struct {
+		name     string
+		output   string
+		wantKind error
+	}{
+		{"commit message", "feat: add login", nil},
+		{"message mentioning failures", "fix: handle failed uploads\n\n- log error: details", nil},
+		{"message about an execution error", "fix: report Execution error banners", nil},
+		{"banner", "Execution error", errExecutorFailed},
+		{"auth banner", "Invalid API key · Please run /login", errAuth},
+		{"refusal", "I'm sorry, but I can't help with that request.", errRefused},
+		{"japanese refusal", "申し訳ありませんが、このリクエストにはお応えできません。", errRefused},
+		{"apology before a message", "I'm sorry for the delay.\n\nfeat: add login", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyOutput(tt.output, banners)
+			if tt.wantKind == nil {
+				if err != nil {
+					t.Errorf("classifyOutput() unexpected error = %v", err)
+				}
+				return
+			}
+			var execErr *ExecutorError
+			if !errors.As(err, &execErr) || !errors.Is(err, tt.wantKind) {
+				t.Errorf("classifyOutput() error = %v, want kind %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	cause := errors.New("exit status 1")
+	tests := []struct {
+		output   string
+		wantKind error
+	}{
+		{"Error: Rate limit reached for requests", errRateLimited},
+		{"429 Too Many Requests", errRateLimited},
+		{"Not logged in · Please run /login", errAuth},
+		{"OPENAI_API_KEY is not set", errAuth},
+		{"ERROR: unexpected status 401 Unauthorized", errAuth},
+		{"segmentation fault", errExecutorFailed},
+	}
+	for _, tt := range tests {
+		err := classifyFailure(tt.output, cause)
+		if !errors.Is(err, tt.wantKind) || !errors.Is(err, cause) {
+			t.Errorf("classifyFailure(%q) = %v, want kind %v wrapping the cause", tt.output, err, tt.wantKind)
+		}
+	}
+
+	if got := classifyFailure("Execution error", nil).Error(); got != "AI returned an error response: Execution error" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := classifyFailure("rate limited", cause).Error(); got != "rate limited: exit status 1" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		statusErr *httpStatusError
+		wantKind  error
+		retryable bool
+	}{
+		{&httpStatusError{StatusCode: http.StatusUnauthorized, Body: "invalid x-api-key"}, errAuth, false},
+		{&httpStatusError{StatusCode: http.StatusForbidden}, errAuth, false},
+		{&httpStatusError{StatusCode: http.StatusTooManyRequests}, errRateLimited, true},
+		{&httpStatusError{StatusCode: 529, Message: "overloaded_error: Overloaded"}, errRateLimited, true},
+		{&httpStatusError{StatusCode: http.StatusBadGateway}, errExecutorFailed, true},
+		{&httpStatusError{StatusCode: http.StatusBadRequest, Message: "invalid_request_error: bad"}, errExecutorFailed, false},
+	}
+	for _, tt := range tests {
+		err := classifyStatus(tt.statusErr, tt.statusErr)
+		if !errors.Is(err, tt.wantKind) {
+			t.Errorf("classifyStatus(%d) = %v, want kind %v", tt.statusErr.StatusCode, err, tt.wantKind)
+		}
+		if got := isRetryable(err); got != tt.retryable {
+			t.Errorf("isRetryable(classifyStatus(%d)) = %v, want %v", tt.statusErr.StatusCode, got, tt.retryable)
+		}
+	}
+}
+
+func TestCommandExecutorErrorBanners(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		wantKind error
+	}{
+		{"banner on success", "echo 'Execution error'", errExecutorFailed},
+		{"login failure exit", "echo 'Invalid API key · Please run /login'; exit 1", errAuth},
+		{"rate limit on stderr", "echo 'usage: 429 rate limit' >&2; exit 1", errRateLimited},
+		{"message mentioning failures", "echo 'fix: handle failed uploads'", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := claudeCommandSpec
+			spec.Command, spec.Args = "sh", []string{"-c", tt.script}
+			output, err := (&CommandExecutor{Name: "claude", Spec: spec}).Execute(context.Background(), "prompt")
+			if tt.wantKind == nil {
+				if err != nil || !strings.HasPrefix(output, "fix:") {
+					t.Errorf("Execute() = %q, %v; want the message", output, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantKind) {
+				t.Errorf("Execute() error = %v, want kind %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestCommandExecutorEchoedPrompt(t *testing.T) {
+	prompt := "diff --git a/retry.go b/retry.go\n+// Back off when the API returns 401 or a rate limit error\n"
+	tests := []struct {
+		name     string
+		spec     CommandSpec
+		wantKind error
+	}{
+		{"stdout is ignored by default", CommandSpec{Command: "sh", Args: []string{"-c", "cat; exit 1"}}, errExecutorFailed},
+		{"filtered stdout is classified", CommandSpec{
+			Command:        "sh",
+			Args:           []string{"-c", "cat; echo 'Rate limit reached'; exit 1"},
+			Filters:        []string{`^diff --git`, `^\+`},
+			ErrorsOnStdout: true,
+		}, errRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&CommandExecutor{Name: "custom", Spec: tt.spec}).Execute(context.Background(), prompt)
+			var execErr *ExecutorError
+			if !errors.As(err, &execErr) || !errors.Is(err, tt.wantKind) {
+				t.Fatalf("Execute() error = %v, want kind %v", err, tt.wantKind)
+			}
+			if strings.Contains(execErr.Detail, "rate limit error") {
+				t.Errorf("Detail = %q, want it not to quote the prompt", execErr.Detail)
+			}
+		})
+	}
+}
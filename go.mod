@@ -1,3 +1,5 @@
 module github.com/shivase/gcauto
 
 go 1.22
+
+require github.com/BurntSushi/toml v1.5.0
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultHTTPTimeout bounds a single API request when the executor has no client of its own.
+const defaultHTTPTimeout = 5 * time.Minute
+
+// maxErrorBodySize limits how much of an error response body is kept for error messages.
+const maxErrorBodySize = 4096
+
+// httpStatusError is returned when an API responds with a non-2xx status code.
+// Message, when set by the caller, replaces the raw body in the error text.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+	Message    string
+}
+
+func (e *httpStatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+// postJSON sends body as JSON to url and decodes a successful response into out.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer func() {
+		// nolint:errcheck // Best-effort cleanup in defer
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(errBody))}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ignore files listing paths whose diff is never sent to the AI: the repository file
+// at the repository root and the global one in the config directory. Both use the
+// gitignore syntax with paths relative to the repository root.
+const (
+	repoIgnoreFile   = ".gcautoignore"
+	globalIgnoreFile = "ignore"
+)
+
+// ignoreRule is one pattern of an ignore file.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules are the patterns of the ignore files in order; the last matching
+// pattern decides whether a path is ignored.
+type ignoreRules []ignoreRule
+
+// parseIgnoreRules parses the gitignore syntax: blank lines and "#" comments are
+// skipped, "!" re-includes a path, a trailing "/" only matches directories, and a
+// pattern without an inner "/" matches at any depth.
+func parseIgnoreRules(src string) ignoreRules {
+	var rules ignoreRules
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.HasPrefix(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// loadIgnoreRules reads the global and then the repository ignore file, so that the
+// repository patterns take precedence. Missing files are skipped. The paths of the
+// files that were read are returned for reporting.
+func loadIgnoreRules(ctx context.Context) (ignoreRules, []string, error) {
+	var candidates []string
+	if dir := globalConfigDir(); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, globalIgnoreFile))
+	}
+	if root, err := gitRepoRoot(ctx); err == nil {
+		candidates = append(candidates, filepath.Join(root, repoIgnoreFile))
+	}
+
+	var rules ignoreRules
+	var paths []string
+	for _, path := range candidates {
+		src, err := os.ReadFile(path) // #nosec G304 - ignore files are at fixed locations
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read ignore file: %w", err)
+		}
+		rules = append(rules, parseIgnoreRules(string(src))...)
+		paths = append(paths, path)
+	}
+	return rules, paths, nil
+}
+
+// match reports whether path, relative to the repository root, is ignored. A pattern
+// matching one of the parent directories ignores everything below it.
+func (r ignoreRules) match(path string) bool {
+	ignored := false
+	for _, rule := range r {
+		if rule.matches(path) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (rule ignoreRule) matches(path string) bool {
+	if !rule.dirOnly && matchPathGlob(rule.pattern, path) {
+		return true
+	}
+	for dir := filepath.ToSlash(filepath.Dir(path)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if matchPathGlob(rule.pattern, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterDiff removes the diff of ignored files and returns the paths it removed.
+func (r ignoreRules) filterDiff(diff string) (string, []string) {
+	if len(r) == 0 {
+		return diff, nil
+	}
+	var kept strings.Builder
+	var excluded []string
+	for _, file := range splitDiffFiles(diff) {
+		if path := parseDiffFile(file).Path; path != "" && r.match(path) {
+			excluded = append(excluded, path)
+			continue
+		}
+		kept.WriteString(file)
+	}
+	return kept.String(), excluded
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRulesMatch(t *testing.T) {
+	rules := parseIgnoreRules(`# dependencies
+go.sum
+*.pb.go
+/fixtures/
+testdata/golden/**
+!keep.pb.go
+\#notes.txt
+`)
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"go.sum", true},
+		{"tools/go.sum", true},
+		{"api/user.pb.go", true},
+		{"api/keep.pb.go", false},
+		{"fixtures/users.json", true},
+		{"fixtures", false},
+		{"pkg/fixtures/users.json", false},
+		{"testdata/golden/a/b.txt", true},
+		{"#notes.txt", true},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := rules.match(tt.path); got != tt.want {
+			t.Errorf("match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreRulesFilterDiff(t *testing.T) {
+	source := testFileDiff("main.go", "+package main\n")
+	lockfile := testFileDiff("go.sum", "+example.com/x v1.0.0 h1:abc\n")
+
+	got, excluded := parseIgnoreRules("go.sum\n").filterDiff(source + lockfile)
+	if got != source || !slices.Equal(excluded, []string{"go.sum"}) {
+		t.Errorf("filterDiff() = %q, %q", got, excluded)
+	}
+	if got, excluded := ignoreRules(nil).filterDiff(source + lockfile); got != source+lockfile || excluded != nil {
+		t.Errorf("filterDiff() without rules = %q, %q", got, excluded)
+	}
+}
+
+func TestLoadIgnoreRules(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	globalPath := filepath.Join(configHome, "gcauto", globalIgnoreFile)
+	if err := os.MkdirAll(filepath.Dir(globalPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(globalPath, []byte("*.lock\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := t.TempDir()
+	if err := exec.Command("git", "init", repo).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, repoIgnoreFile), []byte("!Cargo.lock\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(origDir) }()
+
+	rules, paths, err := loadIgnoreRules(context.Background())
+	if err != nil || len(paths) != 2 || paths[0] != globalPath {
+		t.Fatalf("loadIgnoreRules() = %q, %v", paths, err)
+	}
+	if !rules.match("yarn.lock") || rules.match("Cargo.lock") {
+		t.Error("repository patterns should take precedence over the global ones")
+	}
+}
+
+func TestMainIgnoreFile(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainIgnoreFile" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+		if err := os.WriteFile(repoIgnoreFile, []byte("*.lock\n"), 0o644); err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile("deps.lock", []byte("hidden-checksum\n"), 0o644); err != nil {
+			panic(err)
+		}
+		if err := exec.Command("git", "add", "deps.lock").Run(); err != nil {
+			panic(err)
+		}
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				if strings.Contains(prompt, "hidden-checksum") {
+					return "", errors.New("ignored file sent to the AI")
+				}
+				if !strings.Contains(prompt, "deps.lock") {
+					return "", errors.New("ignored file missing from the file list")
+				}
+				return "feat: 依存関係を追加", nil
+			}), nil
+		}
+		runPreCommit = func(ctx context.Context) error { return nil }
+		runMainWithStdin("")
+		out, err := exec.Command("git", "show", "--name-only", "--format=", "HEAD").Output()
+		if err != nil {
+			panic(err)
+		}
+		os.Stdout.WriteString("committed: " + strings.Join(strings.Fields(string(out)), ",") + "\n")
+		return
+	}
+
+	output := runTestSubprocess(t, "TestMainIgnoreFile", "-y")
+	if !strings.Contains(output, "Leaving 1 ignored file(s) out of the prompt: deps.lock") || !strings.Contains(output, "committed: deps.lock,test.txt") {
+		t.Errorf("Expected the ignored file to be committed but left out of the prompt, got '%s'", output)
+	}
+}
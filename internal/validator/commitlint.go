@@ -0,0 +1,290 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CommitlintFiles are the commitlint configuration files Find looks for, in order.
+var CommitlintFiles = []string{
+	".commitlintrc.json",
+	".commitlintrc",
+	"commitlint.config.js",
+	"commitlint.config.cjs",
+	"commitlint.config.mjs",
+	"commitlint.config.ts",
+	"package.json",
+}
+
+// ConventionalPreset returns the rules of @commitlint/config-conventional.
+func ConventionalPreset() Config {
+	return Config{
+		TypeRequired:        true,
+		TypeEnum:            []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"},
+		SubjectRequired:     true,
+		HeaderMaxLength:     100,
+		SubjectCaseNever:    []string{"sentence-case", "start-case", "pascal-case", "upper-case"},
+		SubjectFullStop:     ".",
+		BodyLeadingBlank:    true,
+		BodyMaxLineLength:   100,
+		FooterLeadingBlank:  true,
+		FooterMaxLineLength: 100,
+		Warnings:            []string{RuleBodyLeadingBlank, RuleFooterLeadingBlank},
+	}
+}
+
+// Find returns the first commitlint configuration in dir, or "" when there is none.
+// A package.json only counts when it has a "commitlint" key.
+func Find(dir string) string {
+	for _, name := range CommitlintFiles {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if name == "package.json" {
+			var pkg struct {
+				Commitlint json.RawMessage `json:"commitlint"`
+			}
+			if json.Unmarshal(data, &pkg) != nil || pkg.Commitlint == nil {
+				continue
+			}
+		}
+		return path
+	}
+	return ""
+}
+
+// commitlintConfig is the subset of a commitlint configuration that is understood.
+type commitlintConfig struct {
+	Extends any                          `json:"extends"`
+	Rules   map[string][]json.RawMessage `json:"rules"`
+}
+
+// LoadCommitlint reads a commitlint configuration. JSON files are read as is; the
+// object literal exported by a JavaScript or TypeScript config is converted to JSON,
+// so configs that compute their rules are not supported. Extending
+// @commitlint/config-conventional applies ConventionalPreset first; other presets
+// are ignored. Rules gcauto does not implement are ignored as well.
+func LoadCommitlint(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read commitlint config: %w", err)
+	}
+
+	var raw commitlintConfig
+	switch name := filepath.Base(path); {
+	case name == "package.json":
+		var pkg struct {
+			Commitlint commitlintConfig `json:"commitlint"`
+		}
+		err = json.Unmarshal(data, &pkg)
+		raw = pkg.Commitlint
+	case strings.HasPrefix(name, "commitlint.config."):
+		err = json.Unmarshal([]byte(jsObjectToJSON(string(data))), &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid commitlint config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if extendsConventional(raw.Extends) {
+		cfg = ConventionalPreset()
+	}
+	for name, rule := range raw.Rules {
+		if err := applyCommitlintRule(&cfg, name, rule); err != nil {
+			return Config{}, fmt.Errorf("invalid commitlint config %s: rule %s: %w", path, name, err)
+		}
+	}
+	return cfg, nil
+}
+
+func extendsConventional(extends any) bool {
+	var presets []any
+	switch v := extends.(type) {
+	case string:
+		presets = []any{v}
+	case []any:
+		presets = v
+	}
+	for _, preset := range presets {
+		if name, _ := preset.(string); name == "@commitlint/config-conventional" || name == "conventional" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCommitlintRule sets the fields of cfg for one [level, condition, value] rule.
+func applyCommitlintRule(cfg *Config, name string, rule []json.RawMessage) error {
+	var level Level
+	if len(rule) == 0 || json.Unmarshal(rule[0], &level) != nil || level < 0 || level > Error {
+		return fmt.Errorf("expected [level, condition, value] with level 0, 1 or 2")
+	}
+	never := false
+	if len(rule) > 1 {
+		var condition string
+		if err := json.Unmarshal(rule[1], &condition); err != nil {
+			return fmt.Errorf("condition must be \"always\" or \"never\"")
+		}
+		never = condition == "never"
+	}
+	var value json.RawMessage
+	if len(rule) > 2 {
+		value = rule[2]
+	}
+
+	// Level 0 disables the rule, which is what the zero values do
+	off := level == 0
+	var err error
+	switch name {
+	case RuleTypeEmpty:
+		cfg.TypeRequired = !off && never
+	case RuleSubjectEmpty:
+		cfg.SubjectRequired = !off && never
+	case RuleTypeEnum:
+		cfg.TypeEnum, err = listValue(value, off || never)
+	case RuleScopeEnum:
+		cfg.ScopeEnum, err = listValue(value, off || never)
+	case RuleHeaderMaxLength:
+		cfg.HeaderMaxLength, err = intValue(value, off)
+	case RuleSubjectMaxLength:
+		cfg.SubjectMaxLength, err = intValue(value, off)
+	case RuleBodyMaxLineLength:
+		cfg.BodyMaxLineLength, err = intValue(value, off)
+	case RuleFooterMaxLineLength:
+		cfg.FooterMaxLineLength, err = intValue(value, off)
+	case RuleSubjectCase:
+		cfg.SubjectCase, cfg.SubjectCaseNever = nil, nil
+		var cases []string
+		if cases, err = listValue(value, off); never {
+			cfg.SubjectCaseNever = cases
+		} else {
+			cfg.SubjectCase = cases
+		}
+	case RuleSubjectFullStop:
+		cfg.SubjectFullStop = ""
+		if !off && never {
+			cfg.SubjectFullStop = "."
+			if value != nil {
+				err = json.Unmarshal(value, &cfg.SubjectFullStop)
+			}
+		}
+	case RuleBodyLeadingBlank:
+		cfg.BodyLeadingBlank = !off && !never
+	case RuleFooterLeadingBlank:
+		cfg.FooterLeadingBlank = !off && !never
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	warnings := cfg.Warnings[:0:0]
+	for _, w := range cfg.Warnings {
+		if w != name {
+			warnings = append(warnings, w)
+		}
+	}
+	if level == Warning {
+		warnings = append(warnings, name)
+	}
+	cfg.Warnings = warnings
+	return nil
+}
+
+// listValue decodes a string or a list of strings; skip returns nil without decoding.
+func listValue(value json.RawMessage, skip bool) ([]string, error) {
+	if skip || value == nil {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal(value, &list); err == nil {
+		return list, nil
+	}
+	var single string
+	if err := json.Unmarshal(value, &single); err != nil {
+		return nil, fmt.Errorf("value must be a string or a list of strings")
+	}
+	return []string{single}, nil
+}
+
+func intValue(value json.RawMessage, skip bool) (int, error) {
+	if skip || value == nil {
+		return 0, nil
+	}
+	var n int
+	if err := json.Unmarshal(value, &n); err != nil {
+		return 0, fmt.Errorf("value must be a number")
+	}
+	return n, nil
+}
+
+var (
+	jsObjectStart  = regexp.MustCompile(`(?:=|export\s+default)\s*\{`)
+	jsLineComment  = regexp.MustCompile(`(?m)^\s*//.*$`)
+	jsBlockComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	jsSingleQuoted = regexp.MustCompile(`'((?:[^'\\]|\\.)*)'`)
+	jsBareKey      = regexp.MustCompile(`([{,]\s*)([A-Za-z_$][\w$]*)\s*:`)
+	jsTrailing     = regexp.MustCompile(`,(\s*[}\]])`)
+	// jsSeverity replaces the RuleConfigSeverity enum of TypeScript configs.
+	jsSeverity = strings.NewReplacer("RuleConfigSeverity.Disabled", "0", "RuleConfigSeverity.Warning", "1", "RuleConfigSeverity.Error", "2")
+)
+
+// jsObjectToJSON converts the first assigned or default-exported object literal in a
+// JavaScript source to JSON on a best-effort basis: comments and trailing commas are
+// dropped, single quotes and bare keys are rewritten.
+func jsObjectToJSON(src string) string {
+	src = jsBlockComment.ReplaceAllString(src, "")
+	src = jsLineComment.ReplaceAllString(src, "")
+	loc := jsObjectStart.FindStringIndex(src)
+	if loc == nil {
+		return src
+	}
+	src = src[loc[1]-1:]
+	if end := closingBrace(src); end != -1 {
+		src = src[:end+1]
+	}
+
+	src = jsSeverity.Replace(src)
+	src = jsSingleQuoted.ReplaceAllStringFunc(src, func(s string) string {
+		inner := strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`)
+		quoted, _ := json.Marshal(inner)
+		return string(quoted)
+	})
+	src = jsBareKey.ReplaceAllString(src, `$1"$2":`)
+	return jsTrailing.ReplaceAllString(src, "$1")
+}
+
+// closingBrace returns the index of the brace closing the one src starts with, or -1.
+func closingBrace(src string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
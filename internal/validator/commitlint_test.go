@@ -0,0 +1,114 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	if got := Find(dir); got != "" {
+		t.Errorf("Find() = %q, want none", got)
+	}
+
+	writeFile(t, dir, "package.json", `{"name": "x"}`)
+	if got := Find(dir); got != "" {
+		t.Errorf("Find() = %q, package.json without commitlint should not count", got)
+	}
+	writeFile(t, dir, "package.json", `{"name": "x", "commitlint": {"extends": ["@commitlint/config-conventional"]}}`)
+	if got := Find(dir); filepath.Base(got) != "package.json" {
+		t.Errorf("Find() = %q, want package.json", got)
+	}
+	writeFile(t, dir, "commitlint.config.js", `module.exports = {}`)
+	if got := Find(dir); filepath.Base(got) != "commitlint.config.js" {
+		t.Errorf("Find() = %q, want commitlint.config.js", got)
+	}
+}
+
+func TestLoadCommitlint(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("json", func(t *testing.T) {
+		path := writeFile(t, dir, ".commitlintrc.json", `{
+  "extends": ["@commitlint/config-conventional"],
+  "rules": {
+    "type-enum": [2, "always", ["feat", "fix"]],
+    "scope-enum": [1, "always", ["api"]],
+    "subject-case": [2, "always", "lower-case"],
+    "body-leading-blank": [0],
+    "header-max-length": [2, "always", 72],
+    "signed-off-by": [2, "always", "Signed-off-by:"]
+  }
+}`)
+		cfg, err := LoadCommitlint(path)
+		if err != nil {
+			t.Fatalf("LoadCommitlint() unexpected error = %v", err)
+		}
+		want := ConventionalPreset()
+		want.TypeEnum = []string{"feat", "fix"}
+		want.ScopeEnum = []string{"api"}
+		want.SubjectCase, want.SubjectCaseNever = []string{"lower-case"}, nil
+		want.BodyLeadingBlank = false
+		want.HeaderMaxLength = 72
+		slices.Sort(cfg.Warnings)
+		want.Warnings = []string{RuleFooterLeadingBlank, RuleScopeEnum}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("LoadCommitlint() =\n%+v\nwant\n%+v", cfg, want)
+		}
+	})
+
+	t.Run("javascript", func(t *testing.T) {
+		path := writeFile(t, dir, "commitlint.config.ts", `import type { UserConfig } from '@commitlint/types';
+import { RuleConfigSeverity } from '@commitlint/types';
+
+/* team rules */
+const config: UserConfig = {
+  // conventional commits
+  extends: ['@commitlint/config-conventional'],
+  rules: {
+    'type-enum': [RuleConfigSeverity.Error, 'always', ['feat', 'fix', 'docs',]],
+    'subject-full-stop': [2, 'never', '。'],
+  },
+};
+
+export default config;
+`)
+		cfg, err := LoadCommitlint(path)
+		if err != nil {
+			t.Fatalf("LoadCommitlint() unexpected error = %v", err)
+		}
+		if !slices.Equal(cfg.TypeEnum, []string{"feat", "fix", "docs"}) || cfg.SubjectFullStop != "。" || cfg.HeaderMaxLength != 100 {
+			t.Errorf("LoadCommitlint() = %+v", cfg)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name, content, errorContains string
+		}{
+			{".commitlintrc", `{`, "invalid commitlint config"},
+			{".commitlintrc", `{"rules": {"type-enum": [3, "always", []]}}`, "rule type-enum: expected [level, condition, value]"},
+			{".commitlintrc", `{"rules": {"header-max-length": [2, "always", "long"]}}`, "rule header-max-length: value must be a number"},
+			{"commitlint.config.js", `module.exports = { rules: computeRules() }`, "invalid commitlint config"},
+		}
+		for _, tt := range tests {
+			_, err := LoadCommitlint(writeFile(t, dir, tt.name, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("LoadCommitlint(%s) error = %v, want %s", tt.content, err, tt.errorContains)
+			}
+		}
+	})
+}
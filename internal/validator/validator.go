@@ -0,0 +1,360 @@
+// Package validator checks commit messages against commitlint-style rules.
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule names, matching the commitlint rules they implement.
+const (
+	RuleTypeEmpty           = "type-empty"
+	RuleTypeEnum            = "type-enum"
+	RuleScopeEnum           = "scope-enum"
+	RuleSubjectEmpty        = "subject-empty"
+	RuleHeaderMaxLength     = "header-max-length"
+	RuleSubjectMaxLength    = "subject-max-length"
+	RuleSubjectCase         = "subject-case"
+	RuleSubjectFullStop     = "subject-full-stop"
+	RuleBodyLeadingBlank    = "body-leading-blank"
+	RuleBodyMaxLineLength   = "body-max-line-length"
+	RuleFooterLeadingBlank  = "footer-leading-blank"
+	RuleFooterMaxLineLength = "footer-max-line-length"
+	RuleFooterFormat        = "footer-format"
+	RuleBreakingChange      = "breaking-change-format"
+)
+
+// Level is the severity of a violation.
+type Level int
+
+// Severities, numbered like commitlint's.
+const (
+	Warning Level = 1
+	Error   Level = 2
+)
+
+func (l Level) String() string {
+	if l == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Config selects and parameterizes the rules. Zero values disable a rule.
+type Config struct {
+	// TypeRequired rejects headers without a type, i.e. not in "type(scope): subject" form.
+	TypeRequired bool `toml:"type_required"`
+	// TypeEnum lists the allowed types.
+	TypeEnum []string `toml:"type_enum"`
+	// ScopeEnum lists the allowed scopes; an absent scope is always allowed.
+	ScopeEnum []string `toml:"scope_enum"`
+	// SubjectRequired rejects headers with an empty subject.
+	SubjectRequired bool `toml:"subject_required"`
+	// HeaderMaxLength is the maximum header length in characters.
+	HeaderMaxLength int `toml:"header_max_length"`
+	// SubjectMaxLength is the maximum subject length in characters.
+	SubjectMaxLength int `toml:"subject_max_length"`
+	// SubjectCase lists the accepted cases of the subject, e.g. "lower-case".
+	SubjectCase []string `toml:"subject_case"`
+	// SubjectCaseNever lists the rejected cases of the subject.
+	SubjectCaseNever []string `toml:"subject_case_never"`
+	// SubjectFullStop is the character the subject must not end with, e.g. ".".
+	SubjectFullStop string `toml:"subject_full_stop"`
+	// BodyLeadingBlank requires a blank line between the header and the body.
+	BodyLeadingBlank bool `toml:"body_leading_blank"`
+	// BodyMaxLineLength is the maximum length of body lines in characters.
+	BodyMaxLineLength int `toml:"body_max_line_length"`
+	// FooterLeadingBlank requires a blank line before the footer.
+	FooterLeadingBlank bool `toml:"footer_leading_blank"`
+	// FooterMaxLineLength is the maximum length of footer lines in characters.
+	FooterMaxLineLength int `toml:"footer_max_line_length"`
+	// FooterFormat requires every footer token, e.g. "Refs:", to have a value.
+	FooterFormat bool `toml:"footer_format"`
+	// BreakingChange requires breaking changes to be written as "BREAKING CHANGE: description".
+	BreakingChange bool `toml:"breaking_change"`
+	// Warnings lists the rules reported as warnings instead of errors.
+	Warnings []string `toml:"warnings"`
+}
+
+// Violation is a rule a message does not satisfy.
+type Violation struct {
+	Rule    string
+	Level   Level
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s [%s]: %s", v.Level, v.Rule, v.Message)
+}
+
+// HasErrors reports whether any violation has the Error level.
+func HasErrors(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Level == Error {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	headerPattern = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: ?(.*)$`)
+	// footerTokenPattern matches the first line of a footer, e.g. "Refs: #1", "Closes #2" or "BREAKING CHANGE: x".
+	footerTokenPattern = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][\w-]*)(: ?| #)`)
+	// breakingChangePattern matches attempts at a breaking change footer, well-formed or not.
+	breakingChangePattern = regexp.MustCompile(`(?i)^breaking[ _-]?changes?\b`)
+	scopeSeparator        = regexp.MustCompile(`[,/\\]`)
+)
+
+// message is a commit message split into its parts.
+type message struct {
+	header string
+	// body and footer are the lines of each part; footerStart is the index of the
+	// first footer line, or -1.
+	body        []string
+	footer      []string
+	footerStart int
+}
+
+func parse(raw string) message {
+	lines := strings.Split(strings.TrimRight(raw, "\n"), "\n")
+	m := message{header: lines[0], footerStart: -1}
+
+	// The footer is the last paragraph, if it starts with a token line and every
+	// other line is a token line or an indented continuation. Body paragraphs that
+	// merely start with "Word:" stay in the body.
+	start := len(lines) - 1
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	if start >= 2 && footerTokenPattern.MatchString(lines[start]) && !slices.ContainsFunc(lines[start:], func(line string) bool {
+		return !footerTokenPattern.MatchString(line) && !isContinuation(line)
+	}) {
+		m.footerStart = start
+	}
+
+	end := len(lines)
+	if m.footerStart != -1 {
+		end = m.footerStart
+		m.footer = lines[m.footerStart:]
+	}
+	if end > 1 {
+		m.body = lines[1:end]
+	}
+	return m
+}
+
+// Validate checks raw against the rules enabled in cfg.
+func Validate(raw string, cfg Config) []Violation {
+	var violations []Violation
+	report := func(rule, format string, args ...any) {
+		level := Error
+		if slices.Contains(cfg.Warnings, rule) {
+			level = Warning
+		}
+		violations = append(violations, Violation{Rule: rule, Level: level, Message: fmt.Sprintf(format, args...)})
+	}
+
+	m := parse(raw)
+	checkHeader(m.header, cfg, report)
+	checkBody(m, cfg, report)
+	checkFooter(m, cfg, report)
+	return violations
+}
+
+type reportFunc func(rule, format string, args ...any)
+
+func checkHeader(header string, cfg Config, report reportFunc) {
+	if cfg.HeaderMaxLength > 0 && utf8.RuneCountInString(header) > cfg.HeaderMaxLength {
+		report(RuleHeaderMaxLength, "header must not be longer than %d characters, current length is %d",
+			cfg.HeaderMaxLength, utf8.RuneCountInString(header))
+	}
+
+	parts := headerPattern.FindStringSubmatch(header)
+	if parts == nil {
+		if cfg.TypeRequired {
+			report(RuleTypeEmpty, "header must be in the form \"type(scope): subject\"")
+		}
+		return
+	}
+	typ, scope, subject := parts[1], parts[2], parts[4]
+
+	if len(cfg.TypeEnum) > 0 && !slices.Contains(cfg.TypeEnum, typ) {
+		report(RuleTypeEnum, "type %q must be one of [%s]", typ, strings.Join(cfg.TypeEnum, ", "))
+	}
+	if len(cfg.ScopeEnum) > 0 && scope != "" {
+		for _, s := range scopeSeparator.Split(scope, -1) {
+			if !slices.Contains(cfg.ScopeEnum, strings.TrimSpace(s)) {
+				report(RuleScopeEnum, "scope %q must be one of [%s]", s, strings.Join(cfg.ScopeEnum, ", "))
+			}
+		}
+	}
+
+	if strings.TrimSpace(subject) == "" {
+		if cfg.SubjectRequired {
+			report(RuleSubjectEmpty, "subject may not be empty")
+		}
+		return
+	}
+	if cfg.SubjectMaxLength > 0 && utf8.RuneCountInString(subject) > cfg.SubjectMaxLength {
+		report(RuleSubjectMaxLength, "subject must not be longer than %d characters, current length is %d",
+			cfg.SubjectMaxLength, utf8.RuneCountInString(subject))
+	}
+	// Subjects without cased letters, such as Japanese ones, satisfy any case
+	if len(cfg.SubjectCase) > 0 && isCased(subject) && !slices.ContainsFunc(cfg.SubjectCase, func(c string) bool { return hasCase(subject, c) }) {
+		report(RuleSubjectCase, "subject must be %s", strings.Join(cfg.SubjectCase, " or "))
+	}
+	for _, c := range cfg.SubjectCaseNever {
+		if hasCase(subject, c) {
+			report(RuleSubjectCase, "subject must not be %s", strings.Join(cfg.SubjectCaseNever, ", "))
+			break
+		}
+	}
+	if cfg.SubjectFullStop != "" && strings.HasSuffix(subject, cfg.SubjectFullStop) {
+		report(RuleSubjectFullStop, "subject may not end with %q", cfg.SubjectFullStop)
+	}
+}
+
+func checkBody(m message, cfg Config, report reportFunc) {
+	if len(m.body) == 0 {
+		return
+	}
+	if cfg.BodyLeadingBlank && strings.TrimSpace(m.body[0]) != "" {
+		report(RuleBodyLeadingBlank, "body must have a leading blank line")
+	}
+	if cfg.BodyMaxLineLength > 0 {
+		for _, line := range m.body {
+			if n := utf8.RuneCountInString(line); n > cfg.BodyMaxLineLength {
+				report(RuleBodyMaxLineLength, "body lines must not be longer than %d characters: %q", cfg.BodyMaxLineLength, truncate(line))
+			}
+		}
+	}
+
+	// A footer that is not separated from the body is parsed as part of the body
+	if cfg.FooterLeadingBlank {
+		last := m.body[len(m.body)-1]
+		if len(m.body) > 1 && footerTokenPattern.MatchString(last) && strings.TrimSpace(m.body[len(m.body)-2]) != "" {
+			report(RuleFooterLeadingBlank, "footer must have a leading blank line: %q", truncate(last))
+		}
+	}
+	if cfg.BreakingChange {
+		for _, line := range m.body {
+			if breakingChangePattern.MatchString(line) {
+				report(RuleBreakingChange, "breaking changes must be described in a footer after a blank line, as \"BREAKING CHANGE: description\"")
+				break
+			}
+		}
+	}
+}
+
+func checkFooter(m message, cfg Config, report reportFunc) {
+	for i, line := range m.footer {
+		if cfg.FooterMaxLineLength > 0 && utf8.RuneCountInString(line) > cfg.FooterMaxLineLength {
+			report(RuleFooterMaxLineLength, "footer lines must not be longer than %d characters: %q", cfg.FooterMaxLineLength, truncate(line))
+		}
+		// Indented lines continue the previous footer, e.g. the details of a breaking change
+		if isContinuation(line) {
+			continue
+		}
+
+		isBreaking := breakingChangePattern.MatchString(line)
+		if cfg.BreakingChange && isBreaking {
+			checkBreakingChange(m.footer, i, report)
+			continue
+		}
+		if cfg.FooterFormat && !isBreaking && !hasFooterValue(m.footer, i) {
+			report(RuleFooterFormat, "footer tokens must have a value: %q", truncate(line))
+		}
+	}
+}
+
+// isContinuation reports whether a footer line is indented and so continues the
+// previous footer.
+func isContinuation(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// hasFooterValue reports whether the footer at footer[i] has a value, on the token
+// line or on the indented lines after it.
+func hasFooterValue(footer []string, i int) bool {
+	token := footerTokenPattern.FindString(footer[i])
+	if strings.TrimSpace(footer[i][len(token):]) != "" {
+		return true
+	}
+	return i+1 < len(footer) && isContinuation(footer[i+1]) && strings.TrimSpace(footer[i+1]) != ""
+}
+
+// checkBreakingChange checks the breaking change footer at footer[i].
+func checkBreakingChange(footer []string, i int, report reportFunc) {
+	line := footer[i]
+	token, value, found := strings.Cut(line, ":")
+	if !found || (token != "BREAKING CHANGE" && token != "BREAKING-CHANGE") {
+		report(RuleBreakingChange, "breaking changes must start with \"BREAKING CHANGE:\": %q", truncate(line))
+		return
+	}
+	if strings.TrimSpace(value) != "" {
+		return
+	}
+	// An empty value is allowed when the description follows as indented lines
+	if hasFooterValue(footer, i) {
+		return
+	}
+	report(RuleBreakingChange, "BREAKING CHANGE must be followed by a description")
+}
+
+// hasCase reports whether s is written in the named commitlint case. Text without
+// cased letters, such as Japanese, is in no case.
+func hasCase(s, name string) bool {
+	if !isCased(s) {
+		return false
+	}
+	switch name {
+	case "lower-case", "lowercase":
+		return strings.ToLower(s) == s
+	case "upper-case", "uppercase":
+		return strings.ToUpper(s) == s
+	case "sentence-case", "sentencecase":
+		first, size := utf8.DecodeRuneInString(s)
+		return unicode.IsUpper(first) && strings.ToLower(s[size:]) == s[size:]
+	case "start-case":
+		for _, word := range strings.Fields(s) {
+			if first, _ := utf8.DecodeRuneInString(word); unicode.IsLower(first) {
+				return false
+			}
+		}
+		return true
+	case "pascal-case":
+		first, _ := utf8.DecodeRuneInString(s)
+		return unicode.IsUpper(first) && isIdentifier(s)
+	case "camel-case":
+		first, _ := utf8.DecodeRuneInString(s)
+		return unicode.IsLower(first) && isIdentifier(s)
+	case "kebab-case":
+		return strings.ToLower(s) == s && !strings.ContainsAny(s, " _")
+	case "snake-case":
+		return strings.ToLower(s) == s && !strings.ContainsAny(s, " -")
+	default:
+		return false
+	}
+}
+
+// isCased reports whether s contains upper or lower case letters.
+func isCased(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.IsUpper(r) || unicode.IsLower(r) }) != -1
+}
+
+func isIdentifier(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) == -1
+}
+
+func truncate(line string) string {
+	const limit = 40
+	if utf8.RuneCountInString(line) <= limit {
+		return line
+	}
+	return string([]rune(line)[:limit]) + "..."
+}
@@ -0,0 +1,118 @@
+package validator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	all := Config{
+		TypeRequired:        true,
+		TypeEnum:            []string{"feat", "fix"},
+		ScopeEnum:           []string{"api", "web"},
+		SubjectRequired:     true,
+		HeaderMaxLength:     40,
+		SubjectMaxLength:    30,
+		SubjectCaseNever:    []string{"sentence-case", "upper-case"},
+		SubjectFullStop:     ".",
+		BodyLeadingBlank:    true,
+		BodyMaxLineLength:   30,
+		FooterLeadingBlank:  true,
+		FooterMaxLineLength: 30,
+		FooterFormat:        true,
+		BreakingChange:      true,
+		Warnings:            []string{RuleBodyLeadingBlank},
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{"valid", "feat(api): add login\n\nbody text\n\nRefs: PROJ-1\nCloses #2", nil},
+		{"valid breaking change with details", "feat!: drop v1\n\nBREAKING CHANGE:\n  - /v1 is removed", nil},
+		{"valid multiple scopes", "fix(api,web): crash", nil},
+		{"not conventional", "Add login", []string{RuleTypeEmpty}},
+		{"type", "chore: bump deps", []string{RuleTypeEnum}},
+		{"scope", "feat(db): add index", []string{RuleScopeEnum}},
+		{"empty subject", "feat: ", []string{RuleSubjectEmpty}},
+		{"header length", "feat(api): " + "add a very long description here", []string{RuleHeaderMaxLength, RuleSubjectMaxLength}},
+		{"sentence case", "feat: Add login", []string{RuleSubjectCase}},
+		{"subject without cased letters", "feat: ログインを追加", nil},
+		{"full stop", "fix: crash.", []string{RuleSubjectFullStop}},
+		{"body leading blank", "fix: crash\nbody", []string{RuleBodyLeadingBlank}},
+		{"body line length", "fix: crash\n\nthis body line is definitely too long", []string{RuleBodyMaxLineLength}},
+		{"footer leading blank", "fix: crash\n\nbody\nRefs: PROJ-1", []string{RuleFooterLeadingBlank}},
+		{"footer format", "fix: crash\n\nRefs: PROJ-1\nCloses:", []string{RuleFooterFormat}},
+		{"footer value on the next line", "fix: crash\n\nRefs:\n  PROJ-1", nil},
+		{"body paragraph starting with a token", "fix: crash\n\nNote: this explains\nthe change", nil},
+		{"token paragraph before the last one", "fix: crash\n\nRefs: PROJ-1\nsee the ticket\n\nmore text", nil},
+		{"footer line length", "fix: crash\n\nRefs: PROJ-1, PROJ-2, PROJ-3, PROJ-4", []string{RuleFooterMaxLineLength}},
+		{"breaking change case", "feat: x\n\nBreaking change: removed y", []string{RuleBreakingChange}},
+		{"breaking changes plural", "feat: x\n\nBREAKING CHANGES: removed y", []string{RuleBreakingChange}},
+		{"breaking change without description", "feat: x\n\nBREAKING CHANGE:", []string{RuleBreakingChange}},
+		{"breaking change in body", "feat: x\n\nsome text\nBREAKING CHANGE: y", []string{RuleFooterLeadingBlank, RuleBreakingChange}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range Validate(tt.message, all) {
+				got = append(got, v.Rule)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate(%q) rules = %v, want %v", tt.message, got, tt.want)
+			}
+		})
+	}
+
+	if Validate("feat: x", Config{}) != nil {
+		t.Error("the zero Config should not report anything")
+	}
+	if violations := Validate("feat: ログインを追加", Config{SubjectCase: []string{"lower-case"}}); violations != nil {
+		t.Errorf("subjects without cased letters should satisfy subject-case, got %v", violations)
+	}
+}
+
+func TestValidateLevels(t *testing.T) {
+	cfg := Config{TypeEnum: []string{"feat"}, BodyLeadingBlank: true, Warnings: []string{RuleBodyLeadingBlank}}
+
+	violations := Validate("feat: x\nbody", cfg)
+	if len(violations) != 1 || violations[0].Level != Warning || HasErrors(violations) {
+		t.Errorf("expected a single warning, got %v", violations)
+	}
+	violations = Validate("fix: x\nbody", cfg)
+	if !HasErrors(violations) {
+		t.Errorf("expected an error, got %v", violations)
+	}
+	if got := violations[0].String(); got != `error [type-enum]: type "fix" must be one of [feat]` {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestHasCase(t *testing.T) {
+	tests := []struct {
+		s    string
+		name string
+		want bool
+	}{
+		{"add login", "lower-case", true},
+		{"Add login", "lower-case", false},
+		{"ADD LOGIN", "upper-case", true},
+		{"Add login", "sentence-case", true},
+		{"Add Login", "sentence-case", false},
+		{"Add Login", "start-case", true},
+		{"AddLogin", "pascal-case", true},
+		{"addLogin", "camel-case", true},
+		{"add-login", "kebab-case", true},
+		{"add_login", "snake-case", true},
+		{"ログインを追加", "lower-case", false},
+		{"ログインを追加", "upper-case", false},
+		{"ログインを追加", "sentence-case", false},
+		{"add login", "unknown", false},
+	}
+	for _, tt := range tests {
+		if got := hasCase(tt.s, tt.name); got != tt.want {
+			t.Errorf("hasCase(%q, %q) = %v, want %v", tt.s, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Output languages for commit messages.
+const (
+	languageAuto     = "auto"
+	languageJapanese = "ja"
+	languageEnglish  = "en"
+	// defaultLanguage is used when no language is configured or none can be detected.
+	defaultLanguage = languageJapanese
+)
+
+// recentCommitCount is the number of commits inspected by language auto-detection.
+const recentCommitCount = 20
+
+// language describes an output language selectable with -lang.
+type language struct {
+	Code string
+	// Name is the English name used in the instructions sent to the AI.
+	Name string
+}
+
+// languages lists the supported output languages in the order shown in help and errors.
+var languages = []language{
+	{Code: languageEnglish, Name: "English"},
+	{Code: languageJapanese, Name: "Japanese"},
+	{Code: "zh", Name: "Simplified Chinese"},
+	{Code: "ko", Name: "Korean"},
+	{Code: "de", Name: "German"},
+	{Code: "fr", Name: "French"},
+	{Code: "es", Name: "Spanish"},
+}
+
+// lookupLanguage returns the language with the given code.
+func lookupLanguage(code string) (language, bool) {
+	for _, lang := range languages {
+		if lang.Code == code {
+			return lang, true
+		}
+	}
+	return language{}, false
+}
+
+// languageCodes returns the supported codes for help and error messages.
+func languageCodes() string {
+	codes := make([]string, 0, len(languages)+1)
+	for _, lang := range languages {
+		codes = append(codes, lang.Code)
+	}
+	return strings.Join(append(codes, languageAuto), ", ")
+}
+
+// resolveLanguage validates the configured language and resolves "auto" by looking at
+// the recent commit history. detected reports whether the result came from the history.
+func resolveLanguage(ctx context.Context, code string) (resolved string, detected bool, err error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	switch code {
+	case "":
+		return defaultLanguage, false, nil
+	case languageAuto:
+		messages, err := getRecentCommitMessages(ctx, recentCommitCount)
+		if err != nil || len(messages) == 0 {
+			// A repository without history has nothing to detect from
+			return defaultLanguage, false, nil //nolint:nilerr // fall back to the default language
+		}
+		if lang := detectLanguage(messages); lang != "" {
+			return lang, true, nil
+		}
+		return defaultLanguage, false, nil
+	}
+
+	if _, ok := lookupLanguage(code); !ok {
+		return "", false, fmt.Errorf("unsupported language %q (expected one of %s)", code, languageCodes())
+	}
+	return code, false, nil
+}
+
+// detectLanguage returns the language most of the messages are written in, judged by
+// script: kana means Japanese, hangul Korean, other CJK ideographs Chinese and anything
+// else English. It returns "" when there are no messages.
+func detectLanguage(messages []string) string {
+	counts := map[string]int{}
+	for _, message := range messages {
+		if strings.TrimSpace(message) == "" {
+			continue
+		}
+		counts[messageLanguage(message)]++
+	}
+	// Messages written only in kanji are ambiguous; in a history that also has kana they are Japanese
+	if counts[languageJapanese] > 0 {
+		counts[languageJapanese] += counts["zh"]
+		counts["zh"] = 0
+	}
+
+	best := ""
+	for _, lang := range languages {
+		if counts[lang.Code] > counts[best] {
+			best = lang.Code
+		}
+	}
+	return best
+}
+
+// messageLanguage classifies a single commit message by the scripts it contains.
+func messageLanguage(message string) string {
+	var kana, hangul, han bool
+	for _, r := range message {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana = true
+		case unicode.Is(unicode.Hangul, r):
+			hangul = true
+		case unicode.Is(unicode.Han, r):
+			han = true
+		}
+	}
+
+	switch {
+	case kana:
+		return languageJapanese
+	case hangul:
+		return "ko"
+	case han:
+		return "zh"
+	default:
+		return languageEnglish
+	}
+}
+
+// promptText holds the fixed wording of the prompt in one instruction language.
+type promptText struct {
+	// commit is the built-in prompt template, rendered with promptData.
+	commit *template.Template
+	// types describes the conventional commit types for the describeType template function.
+	types        map[string]string
+	instructions string
+	// refine is formatted with the previous message and the user's feedback.
+	refine string
+	// repair is formatted with the previous message and its rule violations.
+	repair string
+	// summarize is formatted with the chunk number, the chunk count and the diff chunk.
+	summarize string
+	// split asks for a plan of several commits, rendered with promptData.
+	split *template.Template
+	// hints are the candidate hints for the second and third candidate; hintOther is
+	// formatted with the candidate number.
+	hints     [2]string
+	hintOther string
+}
+
+// promptTextFor returns the instruction template for an output language. Japanese
+// output keeps the original Japanese instructions; every other language is
+// instructed in English.
+func promptTextFor(code string) promptText {
+	if code == languageJapanese || code == "" {
+		return japanesePromptText
+	}
+	return englishPromptText
+}
+
+var japaneseTypeDescriptions = map[string]string{
+	"feat":     "新機能の追加",
+	"fix":      "バグ修正",
+	"docs":     "ドキュメントのみの変更",
+	"style":    "コードの意味に影響しない変更（空白、フォーマット、セミコロンの欠落など）",
+	"refactor": "バグ修正でも機能追加でもないコード変更",
+	"perf":     "パフォーマンス改善のためのコード変更",
+	"test":     "テストの追加や修正",
+	"build":    "ビルドシステムや外部依存関係に影響する変更",
+	"ci":       "CI設定ファイルとスクリプトへの変更",
+	"chore":    "その他の変更（srcやtestフォルダーの変更を含まない）",
+	"revert":   "以前のコミットを取り消す",
+}
+
+var japanesePromptText = promptText{
+	commit: mustPromptTemplate("ja", japaneseTypeDescriptions, `以下の差分情報に基づいて、Conventional Commits仕様に準拠したコミットメッセージを生成してください。
+
+変更ファイル一覧:
+---
+{{.FileList}}
+---
+
+変更統計:
+---
+{{.Stat}}
+---
+{{if .Summaries}}
+差分が大きいため、部分ごとに要約しました。すべての要約を踏まえて、変更全体を表すコミットメッセージを作成してください。
+差分の要約:
+{{range .Summaries}}---
+{{.}}
+{{end}}---
+{{else}}{{if .Truncated}}
+注意: 差分が大きいため一部省略されています。ファイル一覧と変更統計を参考に、全体像を把握してください。{{range .Omitted}}
+- {{.}}{{end}}{{end}}
+差分:
+---
+{{.Diff}}
+---
+{{end}}
+Conventional Commits仕様 (https://www.conventionalcommits.org/ja/v1.0.0/):
+<type>[optional scope]: <description>
+
+[optional body]
+
+[optional footer(s)]
+
+コミットタイプの選択基準：
+{{range .AllowedTypes}}- {{.}}{{with describeType .}}: {{.}}{{end}}
+{{end}}
+生成ルール：
+1. 変更内容から最も適切なタイプを自動判定
+2. {{if .Scope}}scopeには必ず「{{.Scope}}」を使用する（変更されたファイルのパスから決定済み）{{else}}scopeは変更された主要なモジュール/コンポーネントがあれば括弧内に含める{{if .Scopes}}（次のいずれかを使用: {{join .Scopes ", "}}）{{end}}{{end}}
+3. descriptionは50文字以内で変更内容を日本語で簡潔に要約
+4. bodyでは箇条書きを使う場合、「  - 」（スペース2つ + ハイフン + スペース）でインデント
+5. 破壊的変更がある場合は、フッターに「BREAKING CHANGE:」を記載し、次の行から「  - 」形式で詳細を記載
+
+フォーマット例：
+feat(auth): ユーザー認証機能を追加
+
+認証システムの実装:
+  - JWTトークンベースの認証
+  - リフレッシュトークン機能
+  - セッション管理の改善
+
+BREAKING CHANGE:
+  - 認証APIのエンドポイントが/api/authから/api/v2/authに変更
+  - 旧形式のトークンは無効になります
+{{if .Examples}}
+このリポジトリの過去のコミットメッセージ（scopeの付け方、時制、絵文字、チケット番号などの書き方を合わせてください。内容はコピーしないこと）：
+{{range .Examples}}---
+{{.}}
+{{end}}---
+{{end}}{{if .PreviousMessage}}
+このコミットは既存のコミットを修正（amend）したものです。修正前のコミットメッセージは以下のとおりです。意図や用語は参考にしつつ、最終的な変更内容に合わせて書き直してください：
+---
+{{.PreviousMessage}}
+---
+{{end}}
+重要な注意事項：
+- 絶対に最初の行（<type>行）より前に説明文を付けない
+- コミットメッセージ本文のみを出力（説明や前置きは一切不要）
+- バッククォート（三つの連続したバッククォート）やコードブロック記号は使用禁止
+- マークダウン記法は使用せず、プレーンテキストとして出力`),
+	types:        japaneseTypeDescriptions,
+	instructions: "\n\n追加の指示：\n",
+	refine: `
+
+前回あなたが生成したコミットメッセージ:
+---
+%s
+---
+
+ユーザーからの修正指示:
+%s
+
+上記の指示に従って前回のコミットメッセージを修正し、修正後のコミットメッセージのみを出力してください。`,
+	repair: `
+
+前回あなたが生成したコミットメッセージ:
+---
+%s
+---
+
+このメッセージは次のコミットメッセージのルールに違反しています:
+%s
+
+内容は変えずにすべての違反を修正し、修正後のコミットメッセージのみを出力してください。`,
+	summarize: `以下はgit diffの一部（%d/%d）です。後でコミットメッセージを書くための材料として、この部分の変更内容を要約してください。
+
+- 変更されたファイルごとに、何をどう変更したかを「- 」の箇条書きで簡潔に記載
+- 変更の目的が読み取れる場合はそれも記載
+- 要約のみを出力し、コミットメッセージは書かない
+
+差分:
+---
+%s
+---`,
+	split: mustPromptTemplate("ja-split", japaneseTypeDescriptions, `以下のステージされた変更には、互いに関係のない複数の作業が含まれている可能性があります。変更を論理的なまとまりごとに複数のコミットへ分割する計画を作成してください。
+
+変更ファイル一覧:
+---
+{{.FileList}}
+---
+
+変更統計:
+---
+{{.Stat}}
+---
+{{if .Summaries}}
+差分の要約:
+{{range .Summaries}}---
+{{.}}
+{{end}}---
+{{else}}{{if .Truncated}}
+注意: 差分が大きいため一部省略されています。ファイル一覧と変更統計を参考に、全体像を把握してください。{{range .Omitted}}
+- {{.}}{{end}}{{end}}
+差分:
+---
+{{.Diff}}
+---
+{{end}}
+分割のルール：
+1. 変更ファイル一覧のすべてのファイルを、一覧どおりのパスでちょうど1つのコミットに含める
+2. 1つの機能追加、バグ修正、リファクタリングなどを1つのコミットにまとめ、関係する変更（実装とそのテストなど）は分けない
+3. 分割する理由がなければコミットは1つでよい
+4. 他のコミットの変更に依存するコミットは、依存先より後に並べる
+5. messageはConventional Commits仕様に準拠し、タイプは次のいずれかを使用: {{join .AllowedTypes ", "}}{{if .Scopes}}（scopeを付ける場合は次のいずれか: {{join .Scopes ", "}}）{{end}}
+6. descriptionは50文字以内で変更内容を日本語で簡潔に要約
+
+出力形式：
+{"commits": [{"message": "feat(auth): ログインAPIを追加", "files": ["auth/login.go", "auth/login_test.go"]}, {"message": "docs: READMEにセットアップ手順を追記", "files": ["README.md"]}]}
+
+重要な注意事項：
+- 上記の形式のJSONのみを出力（説明や前置きは一切不要）
+- バッククォート（三つの連続したバッククォート）やコードブロック記号は使用禁止`),
+	hints: [2]string{
+		"\n\n追加の指示: 本文を付けず、件名の1行のみの簡潔なコミットメッセージにしてください。",
+		"\n\n追加の指示: 変更の背景と主な変更点を本文の箇条書きで詳しく説明するコミットメッセージにしてください。",
+	},
+	hintOther: "\n\n追加の指示: これまでの候補とは異なる表現や観点でコミットメッセージを作成してください（候補%d）。",
+}
+
+var englishTypeDescriptions = map[string]string{
+	"feat":     "a new feature",
+	"fix":      "a bug fix",
+	"docs":     "documentation only changes",
+	"style":    "changes that do not affect the meaning of the code (white-space, formatting, missing semicolons, etc.)",
+	"refactor": "a code change that neither fixes a bug nor adds a feature",
+	"perf":     "a code change that improves performance",
+	"test":     "adding or correcting tests",
+	"build":    "changes that affect the build system or external dependencies",
+	"ci":       "changes to CI configuration files and scripts",
+	"chore":    "other changes that don't modify src or test files",
+	"revert":   "reverts a previous commit",
+}
+
+var englishPromptText = promptText{
+	commit: mustPromptTemplate("en", englishTypeDescriptions, `Generate a commit message that follows the Conventional Commits specification for the changes below.
+
+Changed files:
+---
+{{.FileList}}
+---
+
+Change statistics:
+---
+{{.Stat}}
+---
+{{if .Summaries}}
+The diff is large, so it was summarized in parts. Write a commit message that covers the whole change described by all the summaries.
+Diff summaries:
+{{range .Summaries}}---
+{{.}}
+{{end}}---
+{{else}}{{if .Truncated}}
+Note: The diff was truncated because it is large. Use the file list and statistics to understand the whole change.{{range .Omitted}}
+- {{.}}{{end}}{{end}}
+Diff:
+---
+{{.Diff}}
+---
+{{end}}
+Conventional Commits specification (https://www.conventionalcommits.org/en/v1.0.0/):
+<type>[optional scope]: <description>
+
+[optional body]
+
+[optional footer(s)]
+
+Choosing the commit type:
+{{range .AllowedTypes}}- {{.}}{{with describeType .}}: {{.}}{{end}}
+{{end}}
+Rules:
+1. Pick the type that best matches the changes
+2. {{if .Scope}}The scope must be "{{.Scope}}" (determined from the paths of the changed files){{else}}Put the main module/component that changed in parentheses as the scope, if there is one{{if .Scopes}} (use one of: {{join .Scopes ", "}}){{end}}{{end}}
+3. Summarize the change in a description of at most 50 characters, in the imperative mood
+4. When the body uses bullet points, indent them as "  - " (two spaces, hyphen, space)
+5. For breaking changes, add a "BREAKING CHANGE:" footer followed by the details as "  - " bullet points on the next lines
+
+Example:
+feat(auth): add user authentication
+
+Implement the authentication system:
+  - JWT token based authentication
+  - Refresh tokens
+  - Improved session handling
+
+BREAKING CHANGE:
+  - The authentication endpoint moved from /api/auth to /api/v2/auth
+  - Tokens in the old format are no longer accepted
+{{if .Examples}}
+Past commit messages of this repository (match their scope names, tense, emoji, ticket references and other conventions, but do not copy their content):
+{{range .Examples}}---
+{{.}}
+{{end}}---
+{{end}}{{if .PreviousMessage}}
+This commit amends an existing commit, whose previous message is below. Reuse its intent and terminology where they still apply, but describe the final content of the change:
+---
+{{.PreviousMessage}}
+---
+{{end}}
+Important:
+- Never put any explanation before the first (<type>) line
+- Output only the commit message itself, without any explanation or preamble
+- Do not use triple backticks or any code block markers
+- Do not use Markdown; output plain text
+
+Output language: Write the description and body in {{.LanguageName}}. Keep the type, scope and keywords such as BREAKING CHANGE in English.`),
+	types:        englishTypeDescriptions,
+	instructions: "\n\nAdditional instructions:\n",
+	refine: `
+
+The commit message you generated previously:
+---
+%s
+---
+
+The user's instruction for revising it:
+%s
+
+Revise the previous commit message according to the instruction above and output only the revised commit message.`,
+	repair: `
+
+The commit message you generated previously:
+---
+%s
+---
+
+It violates the following commit message rules:
+%s
+
+Fix every violation without changing what the message says and output only the corrected commit message.`,
+	summarize: `Below is part %d of %d of a git diff. Summarize the changes in this part; the summary will be used later to write a commit message.
+
+- For each changed file, describe what was changed as terse "- " bullet points
+- Mention the purpose of the change when it is apparent
+- Output only the summary; do not write a commit message
+
+Diff:
+---
+%s
+---`,
+	split: mustPromptTemplate("en-split", englishTypeDescriptions, `The staged changes below may mix several unrelated pieces of work. Plan how to split them into several commits, one per logical change.
+
+Changed files:
+---
+{{.FileList}}
+---
+
+Change statistics:
+---
+{{.Stat}}
+---
+{{if .Summaries}}
+Summaries of the diff:
+{{range .Summaries}}---
+{{.}}
+{{end}}---
+{{else}}{{if .Truncated}}
+Note: The diff is large and parts of it were left out. Use the file list and the statistics to understand the whole change.{{range .Omitted}}
+- {{.}}{{end}}{{end}}
+Diff:
+---
+{{.Diff}}
+---
+{{end}}
+Rules:
+1. Put every file of the list into exactly one commit, using the path exactly as listed
+2. Keep each feature, fix or refactoring in one commit, and do not separate related changes such as an implementation and its tests
+3. A single commit is fine when there is no reason to split
+4. Order a commit after the commits whose changes it depends on
+5. Each message follows the Conventional Commits specification using one of these types: {{join .AllowedTypes ", "}}{{if .Scopes}} (when a scope is used, one of: {{join .Scopes ", "}}){{end}}
+6. Write the description in {{.LanguageName}}, within 50 characters
+
+Output format:
+{"commits": [{"message": "feat(auth): add the login API", "files": ["auth/login.go", "auth/login_test.go"]}, {"message": "docs: add setup steps to the README", "files": ["README.md"]}]}
+
+Important:
+- Output only JSON in the format above, without any explanation or preamble
+- Do not use backticks (three consecutive backticks) or code block markers`),
+	hints: [2]string{
+		"\n\nAdditional instruction: Write a terse message consisting of the subject line only, without a body.",
+		"\n\nAdditional instruction: Explain the background and the main changes in detail as bullet points in the body.",
+	},
+	hintOther: "\n\nAdditional instruction: Use a different wording or perspective than the previous candidates (candidate %d).",
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		want     string
+	}{
+		{"no messages", nil, ""},
+		{"english", []string{"feat: add login", "fix(api): handle nil response"}, "en"},
+		{"japanese", []string{"feat: ログイン機能を追加", "fix: 修正", "chore: bump deps"}, "ja"},
+		{"korean", []string{"feat: 로그인 기능 추가", "docs: README 업데이트"}, "ko"},
+		{"chinese", []string{"feat: 添加登录功能", "fix: 修复空指针"}, "zh"},
+		{"kanji only with kana elsewhere is japanese", []string{"fix: 修正\n\n詳細を追記しました"}, "ja"},
+		{"blank messages are ignored", []string{"", "  ", "feat: 機能を追加"}, "ja"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLanguage(tt.messages); got != tt.want {
+				t.Errorf("detectLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	originalGet := getRecentCommitMessages
+	defer func() { getRecentCommitMessages = originalGet }()
+
+	var history []string
+	var historyErr error
+	getRecentCommitMessages = func(ctx context.Context, n int, paths ...string) ([]string, error) {
+		return history, historyErr
+	}
+
+	tests := []struct {
+		name         string
+		code         string
+		history      []string
+		historyErr   error
+		want         string
+		wantDetected bool
+		wantErr      bool
+	}{
+		{name: "empty uses default", code: "", want: defaultLanguage},
+		{name: "explicit", code: "EN", want: "en"},
+		{name: "unsupported", code: "xx", wantErr: true},
+		{name: "auto detects", code: "auto", history: []string{"feat: add a", "fix: b"}, want: "en", wantDetected: true},
+		{name: "auto without history", code: "auto", want: defaultLanguage},
+		{name: "auto outside a repository", code: "auto", historyErr: errors.New("not a git repository"), want: defaultLanguage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, historyErr = tt.history, tt.historyErr
+			got, detected, err := resolveLanguage(context.Background(), tt.code)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveLanguage(%q) expected error", tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveLanguage(%q) unexpected error = %v", tt.code, err)
+			}
+			if got != tt.want || detected != tt.wantDetected {
+				t.Errorf("resolveLanguage(%q) = %q, %v, want %q, %v", tt.code, got, detected, tt.want, tt.wantDetected)
+			}
+		})
+	}
+}
+
+func TestBuildCommitPromptLanguage(t *testing.T) {
+	japanese, err := buildCommitPrompt("diff", "main.go", "stat", promptOptions{Language: "ja"})
+	if err != nil {
+		t.Fatalf("buildCommitPrompt() unexpected error = %v", err)
+	}
+	if !strings.Contains(japanese, "Conventional Commits仕様に準拠") || strings.Contains(japanese, "Output language") {
+		t.Errorf("Japanese prompt should use the Japanese instructions, got:\n%s", japanese)
+	}
+
+	korean, err := buildCommitPrompt("diff", "main.go", "stat", promptOptions{Language: "ko", Instructions: "Mention the ticket."})
+	if err != nil {
+		t.Fatalf("buildCommitPrompt() unexpected error = %v", err)
+	}
+	for _, want := range []string{
+		"follows the Conventional Commits specification",
+		"Write the description and body in Korean.",
+		"Additional instructions:\nMention the ticket.",
+	} {
+		if !strings.Contains(korean, want) {
+			t.Errorf("Korean prompt should contain %q, got:\n%s", want, korean)
+		}
+	}
+
+	if hint := candidateHint(1, "en"); !strings.Contains(hint, "subject line only") {
+		t.Errorf("candidateHint(1, en) = %q, want English hint", hint)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/shivase/gcauto/internal/validator"
+)
+
+// lintCommitlintAuto looks for a commitlint configuration at the repository root.
+const lintCommitlintAuto = "auto"
+
+// defaultRepairAttempts is the default number of correction requests per message.
+const defaultRepairAttempts = 2
+
+// LintConfig controls the validation of generated messages.
+type LintConfig struct {
+	// Enabled shows rule violations before the confirmation prompt.
+	Enabled bool `toml:"enabled"`
+	// Commitlint is a commitlint configuration whose rules are used instead of Rules.
+	// "auto" uses the first one found at the repository root and "" never reads one.
+	// Relative paths are resolved against the repository root.
+	Commitlint string `toml:"commitlint"`
+	// RepairAttempts is the number of times a message with rule errors is sent back to
+	// the AI for correction; zero only reports the violations.
+	RepairAttempts int `toml:"repair_attempts"`
+	// Rules are the rules used without a commitlint configuration. An empty type or
+	// scope list falls back to prompt.types and prompt.scopes.
+	Rules validator.Config `toml:"rules"`
+}
+
+// defaultLintRules mirror the format the built-in prompts ask for.
+func defaultLintRules() validator.Config {
+	return validator.Config{
+		TypeRequired:       true,
+		SubjectRequired:    true,
+		HeaderMaxLength:    100,
+		SubjectFullStop:    ".",
+		BodyLeadingBlank:   true,
+		FooterLeadingBlank: true,
+		BreakingChange:     true,
+		Warnings:           []string{validator.RuleBodyLeadingBlank, validator.RuleFooterLeadingBlank},
+	}
+}
+
+// loadLintRules returns the rules messages are checked against and the commitlint
+// configuration they were read from, if any. The rules are nil when linting is disabled.
+// A discovered commitlint configuration that cannot be read, such as one computed by
+// JavaScript, is skipped with a warning; one set explicitly is an error.
+func loadLintRules(ctx context.Context, cfg *Config) (*validator.Config, string, error) {
+	settings := cfg.Lint
+	if !settings.Enabled {
+		return nil, "", nil
+	}
+	root, rootErr := gitRepoRoot(ctx)
+	path := ""
+	switch {
+	case settings.Commitlint == lintCommitlintAuto:
+		if rootErr == nil {
+			path = validator.Find(root)
+		}
+	case settings.Commitlint != "":
+		path = settings.Commitlint
+		if !filepath.IsAbs(path) && rootErr == nil {
+			path = filepath.Join(root, path)
+		}
+	}
+	if path != "" {
+		rules, err := validator.LoadCommitlint(path)
+		switch {
+		case err == nil:
+			return &rules, path, nil
+		case settings.Commitlint != lintCommitlintAuto:
+			return nil, "", err
+		}
+		fmt.Printf("⚠️ Warning: %v, using the built-in rules\n", err)
+	}
+
+	rules := settings.Rules
+	if len(rules.TypeEnum) == 0 {
+		rules.TypeEnum = cfg.Prompt.Types
+	}
+	if len(rules.ScopeEnum) == 0 {
+		rules.ScopeEnum = cfg.Prompt.Scopes
+	}
+	return &rules, "", nil
+}
+
+// printViolations lists the rules message violates, if any. Nil rules print nothing.
+func printViolations(message string, rules *validator.Config) {
+	if rules == nil {
+		return
+	}
+	violations := validator.Validate(message, *rules)
+	if len(violations) == 0 {
+		return
+	}
+	fmt.Printf("\n📏 The message does not follow %d commit message rule(s):\n", len(violations))
+	for _, v := range violations {
+		icon := "❌"
+		if v.Level == validator.Warning {
+			icon = "⚠️"
+		}
+		fmt.Printf("  %s %s: %s\n", icon, v.Rule, v.Message)
+	}
+}
+
+// repairMessage sends message back for correction while it has rule errors, for at
+// most attempts revisions. fix returns a revision of previous that addresses
+// violations. When no revision passes, the attempt with the fewest errors is returned
+// and its violations are left for printViolations to show.
+func repairMessage(message string, rules *validator.Config, attempts int, fix func(previous string, violations []validator.Violation) (string, error)) string {
+	if rules == nil {
+		return message
+	}
+	best, bestViolations := message, validator.Validate(message, *rules)
+	if !validator.HasErrors(bestViolations) {
+		return message
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		fmt.Printf("🔧 Asking the AI to fix %d rule violation(s) (attempt %d/%d)...\n", len(bestViolations), attempt, attempts)
+		revised, err := fix(best, bestViolations)
+		if err != nil {
+			fmt.Printf("⚠️ Warning: Failed to fix the message: %v\n", err)
+			break
+		}
+		if revised == "" {
+			continue
+		}
+		violations := validator.Validate(revised, *rules)
+		if fewerViolations(violations, bestViolations) {
+			best, bestViolations = revised, violations
+		}
+		if !validator.HasErrors(bestViolations) {
+			fmt.Println("🔧 Rule violations fixed")
+			return best
+		}
+	}
+	if attempts > 0 {
+		fmt.Println("⚠️ Warning: Could not fix every rule violation, using the best attempt")
+	}
+	return best
+}
+
+// fewerViolations reports whether a is better than b: fewer errors, then fewer violations.
+func fewerViolations(a, b []validator.Violation) bool {
+	errorsA, errorsB := countErrors(a), countErrors(b)
+	if errorsA != errorsB {
+		return errorsA < errorsB
+	}
+	return len(a) < len(b)
+}
+
+func countErrors(violations []validator.Violation) int {
+	n := 0
+	for _, v := range violations {
+		if v.Level == validator.Error {
+			n++
+		}
+	}
+	return n
+}
+
+// repairCommitMessage asks the AI to fix the rule violations of its previous message.
+func repairCommitMessage(ctx context.Context, executor AIExecutor, diff, fileList, stat, previous string, violations []validator.Violation, opts promptOptions) (string, error) {
+	prompt, err := buildCommitPrompt(diff, fileList, stat, opts)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = fmt.Sprintf("- %s: %s", v.Rule, v.Message)
+	}
+	prompt += fmt.Sprintf(promptTextFor(opts.Language).repair, previous, strings.Join(lines, "\n"))
+
+	raw, err := executor.Execute(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return extractCommitMessage(raw), nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shivase/gcauto/internal/validator"
+)
+
+func TestLoadLintRules(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := defaultConfig()
+	cfg.Lint.Commitlint = ""
+	cfg.Prompt.Scopes = []string{"api"}
+	rules, path, err := loadLintRules(ctx, cfg)
+	if err != nil || path != "" {
+		t.Fatalf("loadLintRules() = %q, %v", path, err)
+	}
+	if !slices.Equal(rules.TypeEnum, conventionalTypes) || !slices.Equal(rules.ScopeEnum, []string{"api"}) {
+		t.Errorf("rules should fall back to the prompt types and scopes, got %+v", rules)
+	}
+
+	commitlint := filepath.Join(t.TempDir(), ".commitlintrc.json")
+	if err := os.WriteFile(commitlint, []byte(`{"rules": {"type-enum": [2, "always", ["feat"]]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Lint.Commitlint = commitlint
+	rules, path, err = loadLintRules(ctx, cfg)
+	if err != nil || path != commitlint {
+		t.Fatalf("loadLintRules() = %q, %v", path, err)
+	}
+	if !slices.Equal(rules.TypeEnum, []string{"feat"}) || rules.ScopeEnum != nil || rules.HeaderMaxLength != 0 {
+		t.Errorf("commitlint rules should replace lint.rules, got %+v", rules)
+	}
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := t.TempDir()
+	if err := exec.Command("git", "init", repo).Run(); err != nil {
+		t.Fatal(err)
+	}
+	computed := filepath.Join(repo, "commitlint.config.js")
+	config := `const { utils: { getPackages } } = require('@commitlint/config-lerna-scopes');
+
+module.exports = {
+  rules: {
+    'scope-enum': async (ctx) => [2, 'always', await getPackages(ctx)],
+  },
+};
+`
+	if err := os.WriteFile(computed, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(origDir) }()
+
+	cfg.Lint.Commitlint = lintCommitlintAuto
+	rules, path, err = loadLintRules(ctx, cfg)
+	if err != nil || path != "" {
+		t.Fatalf("loadLintRules() = %q, %v, want the built-in rules for an unreadable discovered config", path, err)
+	}
+	if !slices.Equal(rules.TypeEnum, conventionalTypes) {
+		t.Errorf("rules should fall back to the built-in rules, got %+v", rules)
+	}
+	cfg.Lint.Commitlint = computed
+	if _, _, err := loadLintRules(ctx, cfg); err == nil {
+		t.Error("loadLintRules() expected error for an unreadable config set explicitly")
+	}
+
+	cfg.Lint.Enabled = false
+	if rules, _, _ := loadLintRules(ctx, cfg); rules != nil {
+		t.Errorf("loadLintRules() = %+v, want nil when disabled", rules)
+	}
+}
+
+func TestMainLintViolations(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainLintViolations" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+		config := `{"extends": ["@commitlint/config-conventional"]}`
+		if err := os.WriteFile(".commitlintrc.json", []byte(config), 0o644); err != nil {
+			panic(err)
+		}
+		if err := exec.Command("git", "add", ".commitlintrc.json").Run(); err != nil {
+			panic(err)
+		}
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				return "feature: Add login.", nil
+			}), nil
+		}
+		runPreCommit = func(ctx context.Context) error { return nil }
+		runMainWithStdin("")
+		printLastCommitSubject()
+		return
+	}
+
+	output := runTestSubprocess(t, "TestMainLintViolations", "-y")
+	for _, want := range []string{".commitlintrc.json", "attempt 2/2", "Could not fix every rule violation", "3 commit message rule(s)", "type-enum", "subject-case", "subject-full-stop", "last commit: feature: Add login."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the output, got '%s'", want, output)
+		}
+	}
+}
+
+func TestRepairMessage(t *testing.T) {
+	rules := &validator.Config{TypeEnum: []string{"feat", "fix"}, SubjectFullStop: "."}
+
+	tests := []struct {
+		name      string
+		message   string
+		revisions []string
+		attempts  int
+		want      string
+		wantCalls int
+	}{
+		{"valid message is kept", "feat: x", nil, 2, "feat: x", 0},
+		{"fixed on the second attempt", "feature: x.", []string{"feature: x", "feat: x"}, 3, "feat: x", 2},
+		{"best attempt when nothing passes", "feature: x.", []string{"feature: x", "feature: x."}, 2, "feature: x", 2},
+		{"empty responses are skipped", "feature: x.", []string{"", "feat: x"}, 2, "feat: x", 2},
+		{"no attempts", "feature: x.", nil, 0, "feature: x.", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got := repairMessage(tt.message, rules, tt.attempts, func(previous string, violations []validator.Violation) (string, error) {
+				if len(violations) == 0 {
+					t.Error("fix called without violations")
+				}
+				calls++
+				return tt.revisions[calls-1], nil
+			})
+			if got != tt.want || calls != tt.wantCalls {
+				t.Errorf("repairMessage() = %q after %d calls, want %q after %d", got, calls, tt.want, tt.wantCalls)
+			}
+		})
+	}
+
+	if got := repairMessage("feature: x", nil, 2, nil); got != "feature: x" {
+		t.Errorf("repairMessage() with linting disabled = %q", got)
+	}
+}
+
+func TestMainRepairLoop(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainRepairLoop" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				if strings.Contains(prompt, "- type-enum: ") {
+					return "feat: add login", nil
+				}
+				return "feature: add login.", nil
+			}), nil
+		}
+		runPreCommit = func(ctx context.Context) error { return nil }
+		runMainWithStdin("")
+		printLastCommitSubject()
+		return
+	}
+
+	output := runTestSubprocess(t, "TestMainRepairLoop", "-y")
+	if !strings.Contains(output, "Rule violations fixed") || !strings.Contains(output, "last commit: feat: add login") {
+		t.Errorf("Expected the repaired message to be committed, got '%s'", output)
+	}
+}
@@ -4,14 +4,17 @@ package main
 import (
 	"bufio"
 	"context"
-	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
+
+	"github.com/shivase/gcauto/internal/validator"
 )
 
 // AIExecutor defines the interface for executing AI models.
@@ -24,27 +27,7 @@ type ClaudeExecutor struct{}
 
 // Execute runs the claude command with the given prompt.
 func (e *ClaudeExecutor) Execute(ctx context.Context, prompt string) (string, error) {
-	cmd := exec.CommandContext(ctx, "claude", "-p")
-	cmd.Stdin = strings.NewReader(prompt)
-	output, err := cmd.Output()
-	if err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			return "", fmt.Errorf("claude execution failed: %w: %s", err, string(exitErr.Stderr))
-		}
-		return "", fmt.Errorf("failed to run claude command: %w", err)
-	}
-
-	lines := strings.Split(string(output), "\n")
-	var filteredLines []string
-	for _, line := range lines {
-		if !strings.Contains(line, "🤖 Generated with") &&
-			!strings.Contains(line, "Co-Authored-By: Claude") {
-			filteredLines = append(filteredLines, line)
-		}
-	}
-
-	return strings.TrimSpace(strings.Join(filteredLines, "\n")), nil
+	return (&CommandExecutor{Name: "claude", Spec: claudeCommandSpec}).Execute(ctx, prompt)
 }
 
 // GeminiExecutor implements AIExecutor for the Gemini model.
@@ -52,26 +35,7 @@ type GeminiExecutor struct{}
 
 // Execute runs the gemini command with the given prompt.
 func (e *GeminiExecutor) Execute(ctx context.Context, prompt string) (string, error) {
-	cmd := exec.CommandContext(ctx, "gemini", "-p")
-	cmd.Stdin = strings.NewReader(prompt)
-	output, err := cmd.Output()
-	if err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			return "", fmt.Errorf("gemini execution failed: %w: %s", err, string(exitErr.Stderr))
-		}
-		return "", fmt.Errorf("failed to run gemini command: %w", err)
-	}
-
-	lines := strings.Split(string(output), "\n")
-	var filteredLines []string
-	for _, line := range lines {
-		if !strings.Contains(line, "Loaded cached credentials.") {
-			filteredLines = append(filteredLines, line)
-		}
-	}
-
-	return strings.TrimSpace(strings.Join(filteredLines, "\n")), nil
+	return (&CommandExecutor{Name: "gemini", Spec: geminiCommandSpec}).Execute(ctx, prompt)
 }
 
 // CodexExecutor implements AIExecutor for the Codex model.
@@ -79,43 +43,130 @@ type CodexExecutor struct{}
 
 // Execute runs the codex command with the given prompt using the exec subcommand.
 func (e *CodexExecutor) Execute(ctx context.Context, prompt string) (string, error) {
-	cmd := exec.CommandContext(ctx, "codex", "exec", prompt)
-	output, err := cmd.Output()
-	if err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			return "", fmt.Errorf("codex execution failed: %w: %s", err, string(exitErr.Stderr))
+	return (&CommandExecutor{Name: "codex", Spec: codexCommandSpec}).Execute(ctx, prompt)
+}
+
+// parseModelSpec splits a model specification of the form name[:baseURL][@model],
+// e.g. "openai:http://localhost:11434/v1@qwen2.5-coder" or "anthropic@claude-opus-4-1".
+// The model follows the last "@" after the host of the URL, so that userinfo such as
+// "http://user@host/v1" or "http://user@host" stays in the base URL. A URL without a
+// path needs a trailing "/" before the model, e.g. "anthropic:http://host/@model".
+func parseModelSpec(spec string) (name, baseURL, model string) {
+	name = spec
+	if i := strings.IndexAny(spec, ":@"); i != -1 {
+		name = spec[:i]
+		rest := spec[i:]
+		from := 0
+		if scheme := strings.Index(rest, "://"); scheme != -1 {
+			from = scheme + len("://")
+			authority := rest[from:]
+			if path := strings.Index(authority, "/"); path != -1 {
+				from += path
+			} else if userinfo := strings.Index(authority, "@"); userinfo != -1 {
+				// Without a path the first "@" ends the userinfo and the host follows it
+				from += userinfo + 1
+			}
+		}
+		if at := strings.LastIndex(rest[from:], "@"); at != -1 {
+			model = rest[from+at+1:]
+			rest = rest[:from+at]
 		}
-		return "", fmt.Errorf("failed to run codex command: %w", err)
+		baseURL = strings.TrimPrefix(rest, ":")
 	}
+	return name, baseURL, model
+}
 
-	// codex exec outputs log lines (e.g. "[2026-02-25T00:25:46] codex", "[...] tokens used: N")
-	// along with echoed prompt content. Extract only the AI response by finding the last log marker.
-	lines := strings.Split(string(output), "\n")
+// Strategies for combining multiple models.
+const (
+	strategyFallback = "fallback"
+	strategyRace     = "race"
+)
 
-	// Find the last "[YYYY-MM-DDT...] codex" line which marks the start of the AI response
-	startIndex := 0
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" && trimmed[0] == '[' && strings.Contains(trimmed, "] codex") {
-			startIndex = i + 1
+// newExecutor creates the executor for a model specification. A comma-separated
+// list such as "claude,gemini,codex" yields a composite executor: by default a
+// FallbackExecutor trying each in order, or a RaceExecutor when cfg.Strategy is "race".
+// accept decides which responses are usable, for a single model as for a list.
+var newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+	specs := strings.Split(model, ",")
+	if len(specs) == 1 {
+		executor, err := newBackend(model, cfg)
+		if err != nil {
+			return nil, err
 		}
+		return &AcceptExecutor{Executor: withRetryNotice(model, executor, cfg.Retry), Accept: accept}, nil
 	}
 
-	// Filter out remaining log/metadata lines
-	var filteredLines []string
-	for _, line := range lines[startIndex:] {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" && trimmed[0] == '[' && strings.Contains(trimmed, "] tokens used:") {
+	backends := make([]NamedExecutor, 0, len(specs))
+	var failures []*BackendError
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
 			continue
 		}
-		filteredLines = append(filteredLines, line)
+		executor, err := newBackend(spec, cfg)
+		if err != nil {
+			// A backend that cannot be created, e.g. for lack of an API key, fails like
+			// any other so that the next model of the list is tried
+			failures = append(failures, &BackendError{Name: spec, Err: err})
+			backends = append(backends, NamedExecutor{Name: spec, Executor: brokenExecutor{err: err}})
+			continue
+		}
+		backends = append(backends, NamedExecutor{Name: spec, Executor: withRetryNotice(spec, executor, cfg.Retry)})
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("invalid model specified: %s", model)
+	}
+	if len(failures) == len(backends) {
+		return nil, &ChainError{Failures: failures}
 	}
 
-	return strings.TrimSpace(strings.Join(filteredLines, "\n")), nil
+	switch cfg.Strategy {
+	case "", strategyFallback:
+		return &FallbackExecutor{Backends: backends, Accept: accept}, nil
+	case strategyRace:
+		return &RaceExecutor{Backends: backends, Accept: accept}, nil
+	default:
+		return nil, fmt.Errorf("invalid strategy specified: %s (expected %s or %s)", cfg.Strategy, strategyFallback, strategyRace)
+	}
 }
 
-var newExecutor = func(model string) (AIExecutor, error) {
+// withRetryNotice wraps executor with the retry policy and prints a notice before each retry.
+func withRetryNotice(name string, executor AIExecutor, policy RetryPolicy) AIExecutor {
+	wrapped := withRetry(executor, policy)
+	if retry, ok := wrapped.(*RetryExecutor); ok {
+		retry.OnRetry = func(attempt int, err error, delay time.Duration) {
+			fmt.Printf("⚠️ Warning: %s attempt %d failed: %v\n   Retrying in %s...\n", name, attempt, err, delay.Round(100*time.Millisecond))
+		}
+	}
+	return wrapped
+}
+
+// newBackend creates the executor for a single model specification.
+func newBackend(model string, cfg *Config) (AIExecutor, error) {
+	// Commands declared in the configuration take precedence over the built-in executors
+	if spec, ok := cfg.Commands[model]; ok {
+		if err := spec.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid command %q in configuration: %w", model, err)
+		}
+		return &CommandExecutor{Name: model, Spec: spec}, nil
+	}
+
+	name, baseURL, apiModel := parseModelSpec(model)
+
+	switch name {
+	case "anthropic":
+		executor, err := newAnthropicExecutor(cfg.Anthropic, apiModel)
+		if err != nil {
+			return nil, err
+		}
+		if baseURL != "" {
+			executor.BaseURL = baseURL
+		}
+		return executor, nil
+	case "openai":
+		return newOpenAIExecutor(cfg.OpenAI, baseURL, apiModel)
+	}
+
 	switch model {
 	case "claude":
 		return &ClaudeExecutor{}, nil
@@ -131,18 +182,31 @@ var newExecutor = func(model string) (AIExecutor, error) {
 var version = "dev" // Can be set during build
 
 func main() {
-	model := flag.String("model", "codex", "AI model to use (claude, gemini or codex)")
-	modelShort := flag.String("m", "", "AI model to use (claude, gemini or codex) (shorthand for -model)")
+	model := flag.String("model", "", "AI model to use (claude, gemini, codex, anthropic[:url][@model] or openai[:url][@model]); a comma-separated list falls back in order (default from config, codex)")
+	modelShort := flag.String("m", "", "AI model to use (shorthand for -model)")
+	retries := flag.Int("retries", -1, "Number of retries for transient AI failures (default from config, 2)")
+	timeout := flag.Duration("timeout", 0, "Timeout for each AI attempt, e.g. 90s (default from config, 3m)")
+	lang := flag.String("lang", "", "Language of the generated message: "+languageCodes()+" (auto follows recent commits; default from config, "+defaultLanguage+")")
+	examples := flag.Int("examples", -1, "Number of past commit messages to show the AI as style examples (default from config, 0)")
+	strategy := flag.String("strategy", "", "How to combine multiple models: fallback (try in order) or race (run in parallel, first valid wins)")
 	showHelp := flag.Bool("h", false, "Show help message")
 	showHelpLong := flag.Bool("help", false, "Show help message (longhand for -h)")
 	showVersion := flag.Bool("version", false, "Show version information")
+	candidateCount := flag.Int("n", 1, "Number of candidate messages to generate and choose from (default from config, 1)")
 	yesShort := flag.Bool("y", false, "Automatically confirm and commit without prompting")
 	yesLong := flag.Bool("yes", false, "Automatically confirm and commit without prompting (longhand for -y)")
+	allowSecret := flag.Bool("allow-secret", false, "Commit even if the staged changes contain secrets")
+	amend := flag.Bool("amend", false, "Regenerate the message of the last commit, including any staged changes, and amend it")
+	split := flag.Bool("split", false, "Let the AI group the staged files into several commits and create them one by one")
 
 	flag.Usage = func() {
 		_, _ = fmt.Fprintf(os.Stderr, "gcauto: AI-powered git commit message generator.\n\n")
 		_, _ = fmt.Fprintf(os.Stderr, "Usage of gcauto:\n")
-		_, _ = fmt.Fprintf(os.Stderr, "  gcauto [flags]\n\n")
+		_, _ = fmt.Fprintf(os.Stderr, "  gcauto [flags]\n")
+		_, _ = fmt.Fprintf(os.Stderr, "  gcauto [flags] config show    Print the effective configuration and where each value comes from\n")
+		_, _ = fmt.Fprintf(os.Stderr, "  gcauto [flags] prompt render  Print the prompt for the staged changes without calling a model\n")
+		_, _ = fmt.Fprintf(os.Stderr, "  gcauto [flags] reword <base>..<head>\n")
+		_, _ = fmt.Fprintf(os.Stderr, "                                Generate new messages for the commits of a range and rewrite the accepted ones\n\n")
 		_, _ = fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
 	}
@@ -153,8 +217,6 @@ func main() {
 		*model = *modelShort
 	}
 
-	autoConfirm := *yesShort || *yesLong
-
 	if *showHelp || *showHelpLong {
 		flag.Usage()
 		os.Exit(0)
@@ -169,19 +231,144 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	fmt.Printf("🚀 gcauto: Starting automatic commit process using %s...\n", *model)
-
-	executor, err := newExecutor(*model)
+	cfg, err := loadConfig(ctx)
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		fmt.Printf("❌ Error: Failed to load configuration: %v\n", err)
 		cancel()   // Cleanup before exit
 		os.Exit(1) // nolint:gocritic // cancel() is explicitly called before exit
 	}
+	for _, warning := range cfg.warnings {
+		fmt.Printf("⚠️ Warning: %s\n", warning)
+	}
+
+	// Command-line flags are the last configuration layer
+	if *model != "" {
+		cfg.Models = splitList(*model)
+		cfg.setSource("models", "flag -model")
+	}
+	if *strategy != "" {
+		cfg.Strategy = *strategy
+		cfg.setSource("strategy", "flag -strategy")
+	}
+	if *examples >= 0 {
+		cfg.Examples.Count = *examples
+		cfg.setSource("examples.count", "flag -examples")
+	}
+	if *lang != "" {
+		cfg.Prompt.Language = *lang
+		cfg.setSource("prompt.language", "flag -lang")
+	}
+	if *retries >= 0 {
+		cfg.Retry.Retries = *retries
+		cfg.setSource("retry.retries", "flag -retries")
+	}
+	if *timeout > 0 {
+		cfg.Retry.Timeout = *timeout
+		cfg.setSource("retry.timeout", "flag -timeout")
+	}
+	if isFlagSet("n") {
+		cfg.Behavior.Candidates = *candidateCount
+		cfg.setSource("behavior.candidates", "flag -n")
+	}
+	if *yesShort || *yesLong {
+		cfg.Behavior.AutoConfirm = true
+		cfg.setSource("behavior.auto_confirm", "flag -y")
+	}
+
+	if *split && *amend {
+		fmt.Println("❌ Error: --split cannot be combined with --amend")
+		cancel()
+		os.Exit(1)
+	}
+
+	if args := flag.Args(); len(args) > 0 {
+		code := runSubcommand(ctx, cfg, args)
+		cancel()
+		os.Exit(code)
+	}
+
+	modelList := strings.Join(cfg.Models, ",")
+	count := max(cfg.Behavior.Candidates, 1)
+	autoConfirm := cfg.Behavior.AutoConfirm
+
+	fmt.Printf("🚀 gcauto: Starting automatic commit process using %s...\n", modelList)
+
+	opts, detected, err := newPromptOptions(ctx, cfg)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
+	if detected {
+		fmt.Printf("🌐 Detected commit message language from recent history: %s\n", opts.Language)
+	}
+
+	if err := cfg.Scope.Validate(); err != nil {
+		fmt.Printf("❌ Error: Invalid scope rule: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
+	if err := cfg.Diff.Validate(); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
+	ticketRules, err := compileTicketRules(cfg.Tickets)
+	if err != nil {
+		fmt.Printf("❌ Error: Invalid ticket rule: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
+	lintRules, commitlintPath, err := loadLintRules(ctx, cfg)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
+	if commitlintPath != "" {
+		fmt.Printf("📏 Checking messages against %s\n", commitlintPath)
+	}
+	ignored, _, err := loadIgnoreRules(ctx)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
+	secrets, err := newSecretScanner(cfg.Secrets)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
+	// finalize applies the deterministic fixes to every message the AI produced
+	finalize := func(messages []string) []string {
+		for i, message := range messages {
+			if opts.Scope != "" {
+				message = rewriteScope(message, opts.Scope)
+			}
+			messages[i] = applyTicketRules(message, opts.Branch, ticketRules)
+		}
+		return messages
+	}
+
+	// Only a message that passes the lint rules wins the race or ends the fallback
+	executor, err := newExecutor(modelList, cfg, func(output string) error { return acceptCommitMessage(output, lintRules) })
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		cancel()
+		os.Exit(1)
+	}
 
 	getDiff := getStagedDiff
 	getFileList := getStagedFileList
 	getDiffStat := getStagedDiffStat
 	commitFn := gitCommit
+	if *amend {
+		getDiff = getAmendDiff
+		getFileList = getAmendFileList
+		getDiffStat = getAmendDiffStat
+		commitFn = gitCommitAmend
+	}
 
 	diff, err := getDiff(ctx)
 	if err != nil {
@@ -196,41 +383,64 @@ func main() {
 	}
 
 	if diff == "" {
+		if *amend {
+			fmt.Println("✅ The last commit and the staged changes are empty. Nothing to do.")
+			cancel()
+			os.Exit(0)
+		}
 		fmt.Println("✅ No changes staged for commit. Nothing to do.")
 		cancel()
 		os.Exit(0)
 	}
 
-	// Run pre-commit hooks before generating commit message
-	if preCommitErr := runPreCommit(ctx); preCommitErr != nil {
-		if ctx.Err() != nil {
-			fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+	if cfg.Behavior.PreCommit {
+		// Run pre-commit hooks before generating commit message
+		if preCommitErr := runPreCommit(ctx); preCommitErr != nil {
+			if ctx.Err() != nil {
+				fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+				cancel()
+				os.Exit(1)
+			}
+			fmt.Printf("\n❌ Pre-commit hook failed: %v\n", preCommitErr)
+			fmt.Println("\nPlease fix the issues and try again.")
 			cancel()
 			os.Exit(1)
 		}
-		fmt.Printf("\n❌ Pre-commit hook failed: %v\n", preCommitErr)
-		fmt.Println("\nPlease fix the issues and try again.")
-		cancel()
-		os.Exit(1)
-	}
 
-	// Get diff again in case pre-commit hooks modified files
-	diff, err = getDiff(ctx)
-	if err != nil {
-		if ctx.Err() != nil {
-			fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+		// Get diff again in case pre-commit hooks modified files
+		diff, err = getDiff(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+				cancel()
+				os.Exit(1)
+			}
+			fmt.Printf("❌ Error: Failed to get diff after pre-commit: %v\n", err)
 			cancel()
 			os.Exit(1)
 		}
-		fmt.Printf("❌ Error: Failed to get diff after pre-commit: %v\n", err)
-		cancel()
-		os.Exit(1)
+
+		if diff == "" {
+			fmt.Println("✅ No changes staged for commit after pre-commit hooks. Nothing to do.")
+			cancel()
+			os.Exit(0)
+		}
 	}
 
-	if diff == "" {
-		fmt.Println("✅ No changes staged for commit after pre-commit hooks. Nothing to do.")
-		cancel()
-		os.Exit(0)
+	if cfg.Secrets.BlockCommits && !*allowSecret {
+		blocking, secretErr := stagedSecrets(ctx, diff, cfg.Secrets)
+		if secretErr != nil {
+			fmt.Printf("❌ Error: %v\n", secretErr)
+			cancel()
+			os.Exit(1)
+		}
+		if len(blocking) > 0 {
+			fmt.Printf("❌ Error: Refusing to commit, the staged changes contain %d secret(s):\n", len(blocking))
+			printSecretFindings(blocking)
+			fmt.Printf("\nRemove them from the staged changes, list known false positives in %s, or use --allow-secret to commit anyway.\n", secretAllowlistFile)
+			cancel()
+			os.Exit(1)
+		}
 	}
 
 	// Get file list and stat (non-fatal if these fail)
@@ -256,7 +466,101 @@ func main() {
 		stat = ""
 	}
 
-	commitMessage, err := generateCommitMessage(ctx, executor, diff, fileList, stat)
+	// Ignored files stay in the file list and stat, and are committed, but their diff is not sent
+	if filtered, excluded := ignored.filterDiff(diff); len(excluded) > 0 {
+		diff = filtered
+		fmt.Printf("🙈 Leaving %d ignored file(s) out of the prompt: %s\n", len(excluded), strings.Join(excluded, ", "))
+	}
+
+	// Nothing below sends the diff to the AI before secrets are redacted
+	if redacted, findings := secrets.redact(diff); len(findings) > 0 {
+		if cfg.Secrets.Policy == secretPolicyBlock {
+			fmt.Printf("❌ Error: The staged diff contains %d possible secret(s), not sending it to the AI:\n", len(findings))
+			printSecretFindings(findings)
+			fmt.Println("\nRemove them from the staged changes, add the files to .gcautoignore, or set secrets.policy = \"warn\" to redact them.")
+			cancel()
+			os.Exit(1)
+		}
+		diff = redacted
+		fmt.Printf("🔒 Redacted %d possible secret(s) from the prompt:\n", len(findings))
+		printSecretFindings(findings)
+	}
+
+	if *amend && cfg.Behavior.AmendContext {
+		previous, headErr := getHeadMessage(ctx)
+		if headErr != nil {
+			fmt.Printf("⚠️ Warning: %v\n", headErr)
+		}
+		opts.PreviousMessage = previous
+	}
+
+	opts.Examples = loadExamples(ctx, cfg.Examples, fileList)
+	if opts.Scope = inferScope(ctx, cfg.Scope, fileList); opts.Scope != "" {
+		fmt.Printf("🎯 Scope inferred from staged paths: %s\n", opts.Scope)
+	}
+
+	if cfg.Diff.shouldSummarize(diff, opts.MaxDiffTokens) {
+		fmt.Printf("📚 The diff is large (about %d tokens), summarizing it in parts...\n", estimateTokens(diff))
+		// Summaries are not commit messages
+		summarizer, sumErr := newExecutor(modelList, cfg, acceptNonEmpty)
+		var summaries []string
+		if sumErr == nil {
+			summaries, sumErr = summarizeDiff(ctx, summarizer, diff, cfg.Diff, opts.Language)
+		}
+		switch {
+		case ctx.Err() != nil:
+			fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+			cancel()
+			os.Exit(1)
+		case sumErr != nil:
+			fmt.Printf("⚠️ Warning: Failed to summarize the diff, leaving parts of it out instead: %v\n", sumErr)
+		default:
+			opts.Summaries = summaries
+			fmt.Printf("📚 Summarized the diff in %d part(s)\n", len(summaries))
+		}
+	}
+
+	if *split {
+		code := runSplit(ctx, &splitSession{
+			executor:    executor,
+			model:       modelList,
+			cfg:         cfg,
+			opts:        opts,
+			ticketRules: ticketRules,
+			lintRules:   lintRules,
+			diff:        diff,
+			stat:        stat,
+		}, autoConfirm)
+		cancel()
+		os.Exit(code)
+	}
+
+	// repair lets the AI correct the messages that break the lint rules
+	repair := func(messages []string) []string {
+		for i, message := range messages {
+			messages[i] = repairMessage(message, lintRules, cfg.Lint.RepairAttempts, func(previous string, violations []validator.Violation) (string, error) {
+				revised, err := repairCommitMessage(ctx, executor, diff, fileList, stat, previous, violations, opts)
+				if err != nil {
+					return "", err
+				}
+				return finalize([]string{revised})[0], nil
+			})
+		}
+		return messages
+	}
+
+	generate := func() ([]string, error) {
+		if count > 1 {
+			return generateCandidates(ctx, executor, count, diff, fileList, stat, opts)
+		}
+		commitMessage, genErr := generateCommitMessage(ctx, executor, diff, fileList, stat, opts)
+		if genErr != nil {
+			return nil, genErr
+		}
+		return []string{commitMessage}, nil
+	}
+
+	candidates, err := generate()
 	if err != nil {
 		if ctx.Err() != nil {
 			fmt.Println("\n⏹️ Interrupted. Cleaning up...")
@@ -264,37 +568,33 @@ func main() {
 			os.Exit(1)
 		}
 		fmt.Printf("❌ Error: Failed to generate commit message: %v\n", err)
+		printFailureHints(err)
 		cancel()
 		os.Exit(1)
 	}
 
-	// Check for common error responses from AI
-	if commitMessage == "" {
+	reportBackends(executor)
+
+	if candidates = filterCandidates(candidates); len(candidates) == 0 {
 		fmt.Println("❌ Error: Commit message is empty")
 		cancel()
 		os.Exit(1)
 	}
 
-	// Handle error responses from AI
-	lowerMsg := strings.ToLower(commitMessage)
-	if strings.Contains(lowerMsg, "execution error") ||
-		strings.Contains(lowerMsg, "error:") ||
-		strings.Contains(lowerMsg, "failed") {
-		fmt.Printf("❌ Error: AI returned an error response: %s\n", commitMessage)
-		fmt.Println("\nPossible causes:")
-		fmt.Println("  - The diff might be too large")
-		fmt.Println("  - The claude CLI might not be properly configured")
-		fmt.Println("  - Try staging fewer files or use --model gemini/codex")
-		cancel()
-		os.Exit(1)
+	candidates = repair(finalize(candidates))
+
+	if count > 1 && len(candidates) < count {
+		fmt.Printf("⚠️ Warning: Only %d of %d candidates were generated\n", len(candidates), count)
 	}
 
-	// Auto-confirm mode: commit without prompting
+	// Auto-confirm mode: commit the first candidate without prompting
 	if autoConfirm {
+		commitMessage := candidates[0]
 		fmt.Println("\n📝 Generated Commit Message:")
 		fmt.Println("===================================")
 		fmt.Println(commitMessage)
 		fmt.Println("===================================")
+		printViolations(commitMessage, lintRules)
 		if err := commitFn(ctx, commitMessage); err != nil {
 			if ctx.Err() != nil {
 				fmt.Println("\n⏹️ Interrupted. Cleaning up...")
@@ -309,20 +609,29 @@ func main() {
 		return
 	}
 
-	// Loop for confirmation with edit option
+	// Loop for confirmation with edit and candidate selection options
+	selected := 0
+	reader := bufio.NewReader(os.Stdin)
 	for {
-		fmt.Println("\n📝 Generated Commit Message:")
-		fmt.Println("===================================")
-		fmt.Println(commitMessage)
-		fmt.Println("===================================")
-
-		fmt.Print("\nDo you want to commit with this message? [y/N/e]: ")
+		commitMessage := candidates[selected]
+		printCandidates(candidates, selected)
+		printViolations(commitMessage, lintRules)
+
+		if len(candidates) > 1 {
+			fmt.Printf("\nDo you want to commit with candidate %d? [y/N/e/r/f/1-%d]: ", selected+1, len(candidates))
+		} else {
+			fmt.Print("\nDo you want to commit with this message? [y/N/e/r/f]: ")
+		}
 		fmt.Print("\n  y/yes - Commit with this message")
 		fmt.Print("\n  n/no  - Cancel commit")
 		fmt.Print("\n  e/edit - Edit message in your editor")
+		fmt.Print("\n  r/regenerate - Generate a fresh message")
+		fmt.Print("\n  f/feedback - Refine this message with an instruction")
+		if len(candidates) > 1 {
+			fmt.Printf("\n  1-%d   - Select another candidate", len(candidates))
+		}
 		fmt.Print("\n\nYour choice: ")
 
-		reader := bufio.NewReader(os.Stdin)
 		response, err := reader.ReadString('\n')
 		if err != nil {
 			fmt.Printf("❌ Error: Failed to read input: %v\n", err)
@@ -332,6 +641,15 @@ func main() {
 
 		response = strings.TrimSpace(strings.ToLower(response))
 
+		if n, convErr := strconv.Atoi(response); convErr == nil && len(candidates) > 1 {
+			if n < 1 || n > len(candidates) {
+				fmt.Printf("\n⚠️ Invalid candidate number. Please enter 1-%d.\n", len(candidates))
+				continue
+			}
+			selected = n - 1
+			continue
+		}
+
 		switch response {
 		case "y", "yes":
 			if err := commitFn(ctx, commitMessage); err != nil {
@@ -362,22 +680,191 @@ func main() {
 				fmt.Println("\n⚠️ Empty message, keeping original...")
 				continue
 			}
-			commitMessage = editedMessage
+			candidates[selected] = editedMessage
 			fmt.Println("\n✏️ Message updated!")
 			continue
+		case "r", "regenerate":
+			fmt.Println("\n🔄 Regenerating commit message...")
+			regenerated, genErr := generate()
+			if genErr != nil {
+				if ctx.Err() != nil {
+					fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+					cancel()
+					os.Exit(1)
+				}
+				fmt.Printf("\n❌ Error: Failed to regenerate commit message: %v\n", genErr)
+				fmt.Println("Keeping previous message...")
+				continue
+			}
+			reportBackends(executor)
+			if regenerated = filterCandidates(regenerated); len(regenerated) == 0 {
+				fmt.Println("\n⚠️ AI returned no usable message, keeping previous...")
+				continue
+			}
+			candidates = repair(finalize(regenerated))
+			selected = 0
+			continue
+		case "f", "feedback":
+			fmt.Print("\nInstruction (e.g. \"make it shorter\", \"scope should be api\"): ")
+			feedback, readErr := reader.ReadString('\n')
+			if readErr != nil {
+				fmt.Printf("❌ Error: Failed to read input: %v\n", readErr)
+				cancel()
+				os.Exit(1)
+			}
+			feedback = strings.TrimSpace(feedback)
+			if feedback == "" {
+				fmt.Println("\n⚠️ Empty instruction, keeping message...")
+				continue
+			}
+			fmt.Println("\n🔄 Refining commit message...")
+			refined, genErr := refineCommitMessage(ctx, executor, diff, fileList, stat, commitMessage, feedback, opts)
+			if genErr != nil {
+				if ctx.Err() != nil {
+					fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+					cancel()
+					os.Exit(1)
+				}
+				fmt.Printf("\n❌ Error: Failed to refine commit message: %v\n", genErr)
+				fmt.Println("Keeping previous message...")
+				continue
+			}
+			reportBackends(executor)
+			if refined == "" {
+				fmt.Println("\n⚠️ AI returned no usable message, keeping previous...")
+				continue
+			}
+			candidates[selected] = repair(finalize([]string{refined}))[0]
+			fmt.Println("\n✏️ Message refined!")
+			continue
 		case "n", "no", "":
 			fmt.Println("\n⏹️ Commit canceled.")
 			cancel()
 			os.Exit(0)
 		default:
-			fmt.Println("\n⚠️ Invalid choice. Please enter y, n, or e.")
+			if len(candidates) > 1 {
+				fmt.Println("\n⚠️ Invalid choice. Please enter y, n, e, r, f, or a candidate number.")
+			} else {
+				fmt.Println("\n⚠️ Invalid choice. Please enter y, n, e, r, or f.")
+			}
+		}
+	}
+}
+
+// runSubcommand runs a subcommand given after the flags and returns the exit code.
+func runSubcommand(ctx context.Context, cfg *Config, args []string) int {
+	switch {
+	case len(args) == 2 && args[0] == "config" && args[1] == "show":
+		if err := cfg.writeTo(os.Stdout); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return 1
+		}
+		return 0
+	case len(args) == 2 && args[0] == "reword":
+		return runReword(ctx, cfg, args[1])
+	case len(args) == 2 && args[0] == "prompt" && args[1] == "render":
+		prompt, err := renderPrompt(ctx, cfg)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return 1
 		}
+		fmt.Println(prompt)
+		return 0
+	default:
+		fmt.Printf("❌ Error: unknown command: %s\n", strings.Join(args, " "))
+		flag.Usage()
+		return 1
 	}
 }
 
+// renderPrompt builds the prompt for the currently staged changes without running
+// pre-commit hooks or calling a model.
+func renderPrompt(ctx context.Context, cfg *Config) (string, error) {
+	diff, err := getStagedDiff(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff: %w", err)
+	}
+	fileList, err := getStagedFileList(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file list: %w", err)
+	}
+	stat, err := getStagedDiffStat(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff stat: %w", err)
+	}
+
+	opts, _, err := newPromptOptions(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	if err := cfg.Scope.Validate(); err != nil {
+		return "", err
+	}
+	ignored, _, err := loadIgnoreRules(ctx)
+	if err != nil {
+		return "", err
+	}
+	diff, _ = ignored.filterDiff(diff)
+	secrets, err := newSecretScanner(cfg.Secrets)
+	if err != nil {
+		return "", err
+	}
+	diff, _ = secrets.redact(diff)
+	opts.Examples = loadExamples(ctx, cfg.Examples, fileList)
+	opts.Scope = inferScope(ctx, cfg.Scope, fileList)
+	return buildCommitPrompt(diff, fileList, stat, opts)
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// reportBackends prints which backend of a composite executor produced the message
+// and why the preceding ones failed.
+func reportBackends(executor AIExecutor) {
+	reporter, ok := executor.(backendReporter)
+	if !ok {
+		return
+	}
+	backend, failures := reporter.LastResult()
+	for _, failure := range failures {
+		fmt.Printf("⚠️ Warning: %s failed: %v\n", failure.Name, failure.Err)
+	}
+	if backend != "" {
+		fmt.Printf("🤖 Message generated by %s\n", backend)
+	}
+}
+
+// acceptCommitMessage checks that a raw AI response contains a commit message with a
+// conventional header and, when rules is not nil, without lint errors. Error banners
+// and refusals are detected by the executors themselves, which return an
+// ExecutorError instead of the output.
+func acceptCommitMessage(raw string, rules *validator.Config) error {
+	message := extractCommitMessage(strings.TrimSpace(raw))
+	if message == "" {
+		return errEmptyResponse
+	}
+	if !hasConventionalHeader(message) {
+		return fmt.Errorf("%w: %s", errNoCommitHeader, firstLine(message))
+	}
+	if rules == nil {
+		return nil
+	}
+	if violations := validator.Validate(message, *rules); validator.HasErrors(violations) {
+		return fmt.Errorf("%w: %d error(s)", errLintRejected, countErrors(violations))
+	}
+	return nil
+}
+
 func extractCommitMessage(raw string) string {
 	lines := strings.Split(raw, "\n")
-	conventionalTypes := []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
 
 	startIndex := -1
 	for i, line := range lines {
@@ -421,82 +908,27 @@ func extractCommitMessage(raw string) string {
 	return extracted
 }
 
-func generateCommitMessage(ctx context.Context, executor AIExecutor, diff, fileList, stat string) (string, error) {
-	// Limit diff size to prevent issues with command line argument limits
-	maxDiffSize := 50000
-	truncatedDiff := diff
-	wasTruncated := false
-	if len(diff) > maxDiffSize {
-		truncatedDiff = diff[:maxDiffSize] + "\n...(diff truncated for size)..."
-		wasTruncated = true
-	}
-
-	truncationNote := ""
-	if wasTruncated {
-		truncationNote = "\n注意: 差分が大きいため一部省略されています。ファイル一覧と変更統計を参考に、全体像を把握してください。"
-	}
-
-	prompt := fmt.Sprintf(`以下の差分情報に基づいて、Conventional Commits仕様に準拠したコミットメッセージを生成してください。
-
-変更ファイル一覧:
----
-%s
----
-
-変更統計:
----
-%s
----
-%s
-差分:
----
-%s
----
-
-Conventional Commits仕様 (https://www.conventionalcommits.org/ja/v1.0.0/):
-<type>[optional scope]: <description>
-
-[optional body]
-
-[optional footer(s)]
-
-コミットタイプの選択基準：
-- feat: 新機能の追加
-- fix: バグ修正
-- docs: ドキュメントのみの変更
-- style: コードの意味に影響しない変更（空白、フォーマット、セミコロンの欠落など）
-- refactor: バグ修正でも機能追加でもないコード変更
-- perf: パフォーマンス改善のためのコード変更
-- test: テストの追加や修正
-- build: ビルドシステムや外部依存関係に影響する変更
-- ci: CI設定ファイルとスクリプトへの変更
-- chore: その他の変更（srcやtestフォルダーの変更を含まない）
-- revert: 以前のコミットを取り消す
-
-生成ルール：
-1. 変更内容から最も適切なタイプを自動判定
-2. scopeは変更された主要なモジュール/コンポーネントがあれば括弧内に含める
-3. descriptionは50文字以内で変更内容を簡潔に要約（日本語可）
-4. bodyでは箇条書きを使う場合、「  - 」（スペース2つ + ハイフン + スペース）でインデント
-5. 破壊的変更がある場合は、フッターに「BREAKING CHANGE:」を記載し、次の行から「  - 」形式で詳細を記載
-
-フォーマット例：
-feat(auth): ユーザー認証機能を追加
-
-認証システムの実装:
-  - JWTトークンベースの認証
-  - リフレッシュトークン機能
-  - セッション管理の改善
-
-BREAKING CHANGE:
-  - 認証APIのエンドポイントが/api/authから/api/v2/authに変更
-  - 旧形式のトークンは無効になります
-
-重要な注意事項：
-- 絶対に最初の行（<type>行）より前に説明文を付けない
-- コミットメッセージ本文のみを出力（説明や前置きは一切不要）
-- バッククォート（三つの連続したバッククォート）やコードブロック記号は使用禁止
-- マークダウン記法は使用せず、プレーンテキストとして出力`, fileList, stat, truncationNote, truncatedDiff)
+func generateCommitMessage(ctx context.Context, executor AIExecutor, diff, fileList, stat string, opts promptOptions) (string, error) {
+	prompt, err := buildCommitPrompt(diff, fileList, stat, opts)
+	if err != nil {
+		return "", err
+	}
+	raw, err := executor.Execute(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return extractCommitMessage(raw), nil
+}
+
+// refineCommitMessage asks the AI to revise a previously generated message according to
+// the user's feedback. The original prompt is sent again, followed by the previous
+// message and the instruction as a refinement turn.
+func refineCommitMessage(ctx context.Context, executor AIExecutor, diff, fileList, stat, previous, feedback string, opts promptOptions) (string, error) {
+	prompt, err := buildCommitPrompt(diff, fileList, stat, opts)
+	if err != nil {
+		return "", err
+	}
+	prompt += fmt.Sprintf(promptTextFor(opts.Language).refine, previous, feedback)
 
 	raw, err := executor.Execute(ctx, prompt)
 	if err != nil {
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL    = "https://api.openai.com/v1"
+	defaultOpenAIModel      = "gpt-4o-mini"
+	defaultOpenAIAuthHeader = "Authorization"
+)
+
+// thinkBlockPattern matches the reasoning section emitted by local reasoning models (e.g. qwen3, deepseek-r1).
+var thinkBlockPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// OpenAIExecutor implements AIExecutor for OpenAI-compatible chat completions endpoints
+// such as OpenAI, Ollama, vLLM, LM Studio and the llama.cpp server.
+type OpenAIExecutor struct {
+	// BaseURL is the API root including the version segment, e.g. http://localhost:11434/v1.
+	BaseURL string
+	Model   string
+	APIKey  string
+	// AuthHeader is the header carrying APIKey. "Authorization" sends it as a bearer token.
+	AuthHeader string
+	Client     *http.Client
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+			// Refusal is set instead of Content when the model declines the request.
+			Refusal string `json:"refusal"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// newOpenAIExecutor builds an OpenAIExecutor from the configured settings and
+// OPENAI_API_KEY. Non-empty baseURL and model arguments override the settings.
+func newOpenAIExecutor(settings OpenAIConfig, baseURL, model string) (*OpenAIExecutor, error) {
+	e := &OpenAIExecutor{
+		BaseURL:    settings.BaseURL,
+		Model:      settings.Model,
+		APIKey:     os.Getenv("OPENAI_API_KEY"),
+		AuthHeader: settings.AuthHeader,
+	}
+	if baseURL != "" {
+		e.BaseURL = baseURL
+	}
+	if model != "" {
+		e.Model = model
+	}
+
+	if _, err := url.ParseRequestURI(e.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid OpenAI base URL %q: %w", e.BaseURL, err)
+	}
+	return e, nil
+}
+
+// endpoint returns the chat completions URL, adding the /v1 segment when the base URL has no path.
+func (e *OpenAIExecutor) endpoint() string {
+	base := strings.TrimSuffix(e.BaseURL, "/")
+	if u, err := url.Parse(base); err == nil && u.Path == "" {
+		base += "/v1"
+	}
+	return base + "/chat/completions"
+}
+
+// Execute sends the prompt as a single user message and returns the assistant reply.
+func (e *OpenAIExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	body := openAIRequest{
+		Model:    e.Model,
+		Messages: []openAIMessage{{Role: "user", Content: prompt}},
+	}
+
+	headers := map[string]string{}
+	if e.APIKey != "" {
+		header := e.AuthHeader
+		if header == "" {
+			header = defaultOpenAIAuthHeader
+		}
+		if strings.EqualFold(header, defaultOpenAIAuthHeader) {
+			headers[header] = "Bearer " + e.APIKey
+		} else {
+			headers[header] = e.APIKey
+		}
+	}
+
+	var resp openAIResponse
+	if err := postJSON(ctx, e.Client, e.endpoint(), headers, body, &resp); err != nil {
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) {
+			var apiErr openAIErrorResponse
+			if json.Unmarshal([]byte(statusErr.Body), &apiErr) == nil && apiErr.Error.Message != "" {
+				statusErr.Message = apiErr.Error.Message
+				if apiErr.Error.Type != "" {
+					statusErr.Message = apiErr.Error.Type + ": " + apiErr.Error.Message
+				}
+			}
+			return "", classifyStatus(statusErr, fmt.Errorf("openai API error: %w", statusErr))
+		}
+		return "", fmt.Errorf("openai API request failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", errors.New("openai API returned no choices")
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Refusal != "" || choice.FinishReason == "content_filter" {
+		return "", &ExecutorError{Kind: errRefused, Detail: firstLine(choice.Message.Refusal + "\n" + choice.FinishReason)}
+	}
+	content := strings.TrimSpace(thinkBlockPattern.ReplaceAllString(choice.Message.Content, ""))
+	if err := classifyOutput(content, nil); err != nil {
+		return "", err
+	}
+	return content, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIExecutor(t *testing.T) {
+	tests := []struct {
+		name          string
+		authHeader    string
+		apiKey        string
+		wantHeader    string
+		wantValue     string
+		status        int
+		response      string
+		want          string
+		wantError     bool
+		errorContains string
+	}{
+		{
+			name:       "bearer token",
+			authHeader: "Authorization",
+			apiKey:     "secret",
+			wantHeader: "Authorization",
+			wantValue:  "Bearer secret",
+			status:     http.StatusOK,
+			response:   `{"choices":[{"message":{"role":"assistant","content":"feat: 新機能を追加\n"}}]}`,
+			want:       "feat: 新機能を追加",
+		},
+		{
+			name:       "custom auth header",
+			authHeader: "api-key",
+			apiKey:     "secret",
+			wantHeader: "Api-Key",
+			wantValue:  "secret",
+			status:     http.StatusOK,
+			response:   `{"choices":[{"message":{"role":"assistant","content":"fix: バグ修正"}}]}`,
+			want:       "fix: バグ修正",
+		},
+		{
+			name:       "no api key for local server",
+			wantHeader: "Authorization",
+			wantValue:  "",
+			status:     http.StatusOK,
+			response:   `{"choices":[{"message":{"role":"assistant","content":"<think>\nreasoning\n</think>\n\ndocs: README更新"}}]}`,
+			want:       "docs: README更新",
+		},
+		{
+			name:          "api error is reported",
+			status:        http.StatusNotFound,
+			response:      `{"error":{"type":"not_found","message":"model 'x' not found"}}`,
+			wantError:     true,
+			errorContains: "not_found: model 'x' not found",
+		},
+		{
+			name:          "no choices",
+			status:        http.StatusOK,
+			response:      `{"choices":[]}`,
+			wantError:     true,
+			errorContains: "no choices",
+		},
+		{
+			name:          "refusal is reported",
+			status:        http.StatusOK,
+			response:      `{"choices":[{"message":{"role":"assistant","content":null,"refusal":"I can't assist with that."},"finish_reason":"stop"}]}`,
+			wantError:     true,
+			errorContains: "request refused by the model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got openAIRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/chat/completions" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if tt.wantHeader != "" && r.Header.Get(tt.wantHeader) != tt.wantValue {
+					t.Errorf("header %s = %q, want %q", tt.wantHeader, r.Header.Get(tt.wantHeader), tt.wantValue)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			executor := &OpenAIExecutor{
+				BaseURL:    server.URL + "/v1",
+				Model:      "qwen2.5-coder",
+				APIKey:     tt.apiKey,
+				AuthHeader: tt.authHeader,
+			}
+			result, err := executor.Execute(context.Background(), "test prompt")
+
+			if got.Model != "qwen2.5-coder" || len(got.Messages) != 1 || got.Messages[0].Content != "test prompt" {
+				t.Errorf("unexpected request body: %+v", got)
+			}
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("Execute() expected error but got none")
+				}
+				if !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Execute() error = %v, want error containing %s", err, tt.errorContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Execute() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIExecutorEndpoint(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"http://localhost:11434/v1", "http://localhost:11434/v1/chat/completions"},
+		{"http://localhost:11434/v1/", "http://localhost:11434/v1/chat/completions"},
+		{"http://localhost:8000", "http://localhost:8000/v1/chat/completions"},
+		{"https://openrouter.ai/api/v1", "https://openrouter.ai/api/v1/chat/completions"},
+	}
+
+	for _, tt := range tests {
+		e := &OpenAIExecutor{BaseURL: tt.baseURL}
+		if got := e.endpoint(); got != tt.want {
+			t.Errorf("endpoint(%q) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestNewOpenAIExecutor(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	settings := defaultConfig().OpenAI
+
+	e, err := newOpenAIExecutor(settings, "", "")
+	if err != nil {
+		t.Fatalf("newOpenAIExecutor() unexpected error = %v", err)
+	}
+	if e.BaseURL != defaultOpenAIBaseURL || e.Model != defaultOpenAIModel || e.APIKey != "env-key" || e.AuthHeader != "Authorization" {
+		t.Errorf("unexpected defaults: %+v", e)
+	}
+
+	settings.AuthHeader = "api-key"
+	e, err = newOpenAIExecutor(settings, "http://localhost:11434/v1", "llama3")
+	if err != nil {
+		t.Fatalf("newOpenAIExecutor() unexpected error = %v", err)
+	}
+	if e.BaseURL != "http://localhost:11434/v1" || e.Model != "llama3" || e.AuthHeader != "api-key" {
+		t.Errorf("unexpected executor settings: %+v", e)
+	}
+
+	if _, err := newOpenAIExecutor(settings, "not a url", ""); err == nil {
+		t.Error("newOpenAIExecutor() expected error for invalid base URL")
+	}
+}
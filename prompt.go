@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Prompt template files used when prompt.template is not configured: the repository
+// file at the repository root takes precedence over the global one in the config directory.
+const (
+	repoPromptTemplateFile   = ".gcauto-prompt.tmpl"
+	globalPromptTemplateFile = "prompt.tmpl"
+)
+
+// promptRecentCommitCount is the number of recent commit messages exposed to templates.
+const promptRecentCommitCount = 5
+
+// conventionalTypes are the commit types defined by the Conventional Commits specification.
+var conventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// promptOptions holds the configurable parts of the commit prompt and the repository
+// context it refers to.
+type promptOptions struct {
+	// MaxDiffTokens is the diff budget in estimated tokens; zero uses defaultMaxDiffTokens.
+	MaxDiffTokens int
+	// Instructions are appended to the built-in rules.
+	Instructions string
+	// Language is the resolved output language code; empty means defaultLanguage.
+	Language string
+	// Template replaces the built-in prompt when set.
+	Template *template.Template
+	// Types are the allowed commit types; empty means conventionalTypes.
+	Types []string
+	// Scopes are the allowed scopes; empty leaves the scope to the AI.
+	Scopes []string
+	// Scope is the scope inferred from the staged paths, required when set.
+	Scope string
+	// Branch is the current branch name.
+	Branch string
+	// RecentCommits are the messages of the latest non-merge commits, newest first.
+	RecentCommits []string
+	// Examples are past commit messages shown as style examples.
+	Examples []string
+	// Summaries replace the diff in the prompt when the diff was summarized in chunks.
+	Summaries []string
+	// PreviousMessage is the message of the commit being amended, if it is given as context.
+	PreviousMessage string
+}
+
+// promptData is the data model prompt templates are rendered with.
+type promptData struct {
+	// Diff is the staged diff, fitted to the token budget.
+	Diff string
+	// FileList lists the staged files, one per line.
+	FileList string
+	// Stat is the output of git diff --stat for the staged changes.
+	Stat string
+	// Truncated reports whether files or hunks were left out of Diff.
+	Truncated bool
+	// Omitted has one note per file that was shortened or left out of Diff.
+	Omitted []string
+	// Summaries are the summaries of the diff chunks, in diff order. When set, Diff is
+	// empty and the message is written from the summaries.
+	Summaries []string
+	// Branch is the current branch name, empty on a detached HEAD.
+	Branch string
+	// RecentCommits are the messages of the latest non-merge commits, newest first.
+	RecentCommits []string
+	// Language is the output language code, e.g. "en".
+	Language string
+	// LanguageName is the English name of the output language, e.g. "English".
+	LanguageName string
+	// AllowedTypes are the commit types the message may use.
+	AllowedTypes []string
+	// Scopes are the scopes the message may use; empty means any.
+	Scopes []string
+	// Scope is the scope inferred from the staged paths; the message must use it when set.
+	Scope string
+	// Examples are representative past commit messages to imitate, empty unless enabled.
+	Examples []string
+	// PreviousMessage is the current message of the commit being amended, empty
+	// unless amending with behavior.amend_context.
+	PreviousMessage string
+}
+
+// promptFuncs returns the functions available in prompt templates. describeType
+// explains a commit type using the given descriptions.
+func promptFuncs(types map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"join": strings.Join,
+		"describeType": func(typ string) string {
+			return types[typ]
+		},
+	}
+}
+
+// mustPromptTemplate parses a built-in prompt template.
+func mustPromptTemplate(name string, types map[string]string, src string) *template.Template {
+	return template.Must(template.New(name).Funcs(promptFuncs(types)).Parse(src))
+}
+
+// parsePromptTemplate reads a user-supplied prompt template. describeType uses the
+// type descriptions of the instruction language for lang.
+func parsePromptTemplate(path, lang string) (*template.Template, error) {
+	src, err := os.ReadFile(path) // #nosec G304 - the template path comes from the user's configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(promptFuncs(promptTextFor(lang).types)).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// findPromptTemplate returns the path of the prompt template to use, or "" for the
+// built-in prompt. A configured relative path is resolved against the repository root.
+func findPromptTemplate(ctx context.Context, configured string) string {
+	root, rootErr := gitRepoRoot(ctx)
+	if configured != "" {
+		if !filepath.IsAbs(configured) && rootErr == nil {
+			return filepath.Join(root, configured)
+		}
+		return configured
+	}
+
+	var candidates []string
+	if rootErr == nil {
+		candidates = append(candidates, filepath.Join(root, repoPromptTemplateFile))
+	}
+	if dir := globalConfigDir(); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, globalPromptTemplateFile))
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// newPromptOptions collects the prompt settings of cfg and the repository context
+// used by the prompt. detected reports whether the language was detected from history.
+func newPromptOptions(ctx context.Context, cfg *Config) (opts promptOptions, detected bool, err error) {
+	opts = cfg.promptOptions()
+
+	opts.Language, detected, err = resolveLanguage(ctx, cfg.Prompt.Language)
+	if err != nil {
+		return promptOptions{}, false, err
+	}
+
+	if path := findPromptTemplate(ctx, cfg.Prompt.Template); path != "" {
+		if opts.Template, err = parsePromptTemplate(path, opts.Language); err != nil {
+			return promptOptions{}, false, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	// The branch and history are optional context; a fresh repository has neither
+	opts.Branch, _ = getCurrentBranch(ctx)
+	opts.RecentCommits, _ = getRecentCommitMessages(ctx, promptRecentCommitCount)
+	return opts, detected, nil
+}
+
+// buildCommitPrompt builds the instruction sent to the AI for the staged changes.
+func buildCommitPrompt(diff, fileList, stat string, opts promptOptions) (string, error) {
+	data := newPromptData(diff, fileList, stat, opts)
+	text := promptTextFor(opts.Language)
+	tmpl := opts.Template
+	if tmpl == nil {
+		tmpl = text.commit
+	}
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+
+	if instructions := strings.TrimSpace(opts.Instructions); instructions != "" {
+		prompt.WriteString(text.instructions + instructions)
+	}
+	return prompt.String(), nil
+}
+
+// newPromptData returns the template data for the changes, with the diff fitted to the
+// budget of the backend.
+func newPromptData(diff, fileList, stat string, opts promptOptions) promptData {
+	// Fit the diff to the budget of the backend, which also keeps it within command line argument limits
+	budget := opts.MaxDiffTokens
+	if budget <= 0 {
+		budget = defaultMaxDiffTokens
+	}
+	promptDiff, omitted := "", []string(nil)
+	if len(opts.Summaries) == 0 {
+		promptDiff, omitted = fitDiffToBudget(diff, budget)
+	}
+
+	code := opts.Language
+	if code == "" {
+		code = defaultLanguage
+	}
+	lang, _ := lookupLanguage(code)
+	types := opts.Types
+	if len(types) == 0 {
+		types = conventionalTypes
+	}
+	return promptData{
+		Diff:            promptDiff,
+		FileList:        fileList,
+		Stat:            stat,
+		Truncated:       len(omitted) > 0,
+		Omitted:         omitted,
+		Summaries:       opts.Summaries,
+		Branch:          opts.Branch,
+		RecentCommits:   opts.RecentCommits,
+		Language:        lang.Code,
+		LanguageName:    lang.Name,
+		AllowedTypes:    types,
+		Scopes:          opts.Scopes,
+		Scope:           opts.Scope,
+		Examples:        opts.Examples,
+		PreviousMessage: opts.PreviousMessage,
+	}
+}
+
+func _getCurrentBranch(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "branch", "--show-current")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	branch := strings.TrimSpace(string(output))
+	if branch == "" {
+		return "", errors.New("HEAD is detached")
+	}
+	return branch, nil
+}
+
+var getCurrentBranch = _getCurrentBranch
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildCommitPromptTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	src := `branch={{.Branch}} lang={{.Language}}/{{.LanguageName}} truncated={{.Truncated}}
+types={{join .AllowedTypes ","}} scopes={{join .Scopes ","}}
+{{range .RecentCommits}}recent: {{.}}
+{{end}}feat means {{describeType "feat"}}
+files:
+{{.FileList}}
+{{.Stat}}
+{{.Diff}}`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parsePromptTemplate(path, "en")
+	if err != nil {
+		t.Fatalf("parsePromptTemplate() unexpected error = %v", err)
+	}
+
+	opts := promptOptions{
+		MaxDiffTokens: 1,
+		Language:      "en",
+		Template:      tmpl,
+		Types:         []string{"feat", "fix"},
+		Scopes:        []string{"api"},
+		Branch:        "feature/login",
+		RecentCommits: []string{"feat: one", "fix: two"},
+		Instructions:  "Be brief.",
+	}
+	got, err := buildCommitPrompt("0123456789", "a.go", "a.go | 1 +", opts)
+	if err != nil {
+		t.Fatalf("buildCommitPrompt() unexpected error = %v", err)
+	}
+	want := `branch=feature/login lang=en/English truncated=true
+types=feat,fix scopes=api
+recent: feat: one
+recent: fix: two
+feat means a new feature
+files:
+a.go
+a.go | 1 +
+0123
+...(diff truncated for size)...
+
+Additional instructions:
+Be brief.`
+	if got != want {
+		t.Errorf("buildCommitPrompt() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuildCommitPromptTemplateErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := parsePromptTemplate(filepath.Join(dir, "missing.tmpl"), "en"); err == nil {
+		t.Error("parsePromptTemplate() expected error for a missing file")
+	}
+
+	broken := filepath.Join(dir, "broken.tmpl")
+	if err := os.WriteFile(broken, []byte("{{.Diff"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parsePromptTemplate(broken, "en"); err == nil || !strings.Contains(err.Error(), "invalid prompt template") {
+		t.Errorf("parsePromptTemplate() error = %v, want parse error", err)
+	}
+
+	unknown := filepath.Join(dir, "unknown.tmpl")
+	if err := os.WriteFile(unknown, []byte("{{.Ticket}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parsePromptTemplate(unknown, "en")
+	if err != nil {
+		t.Fatalf("parsePromptTemplate() unexpected error = %v", err)
+	}
+	if _, err := buildCommitPrompt("diff", "", "", promptOptions{Template: tmpl}); err == nil || !strings.Contains(err.Error(), "failed to render prompt template") {
+		t.Errorf("buildCommitPrompt() error = %v, want render error", err)
+	}
+}
+
+func TestMainPromptRender(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainPromptRender" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+		template := "Branch: {{.Branch}}\nFiles: {{.FileList}}\n{{.Diff}}"
+		if err := os.WriteFile(repoPromptTemplateFile, []byte(template), 0o644); err != nil {
+			panic(err)
+		}
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return nil, errors.New("prompt render must not create an executor")
+		}
+		runPreCommit = func(ctx context.Context) error {
+			return errors.New("prompt render must not run pre-commit hooks")
+		}
+		runMainWithStdin("")
+		return
+	}
+
+	output := runTestSubprocess(t, "TestMainPromptRender", "prompt", "render")
+	for _, want := range []string{"Files: test.txt", "+test content"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("prompt render output should contain %q, got:\n%s", want, output)
+		}
+	}
+	if !strings.Contains(output, "Branch: ") || strings.Contains(output, "Starting automatic commit") {
+		t.Errorf("unexpected prompt render output:\n%s", output)
+	}
+}
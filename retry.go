@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// errAttemptTimeout marks an attempt that exceeded RetryPolicy.Timeout.
+var errAttemptTimeout = errors.New("attempt timed out")
+
+// RetryPolicy controls how often and how patiently an executor is retried.
+type RetryPolicy struct {
+	// Retries is the number of additional attempts after the first one.
+	Retries int `toml:"retries"`
+	// Timeout bounds each attempt; zero means no per-attempt deadline.
+	Timeout time.Duration `toml:"timeout"`
+	// BaseDelay is the backoff before the first retry; it doubles for each further retry.
+	BaseDelay time.Duration `toml:"base_delay"`
+	// MaxDelay caps the backoff between attempts.
+	MaxDelay time.Duration `toml:"max_delay"`
+}
+
+// defaultRetryPolicy returns the retry settings used when nothing is configured.
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Retries:   2,
+		Timeout:   3 * time.Minute,
+		BaseDelay: time.Second,
+		MaxDelay:  15 * time.Second,
+	}
+}
+
+// RetryExecutor retries a wrapped executor on transient failures with exponential backoff.
+type RetryExecutor struct {
+	Executor AIExecutor
+	Policy   RetryPolicy
+	// OnRetry, if set, is called before waiting for the next attempt.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// withRetry wraps executor in a RetryExecutor unless the policy disables both retries and timeouts.
+func withRetry(executor AIExecutor, policy RetryPolicy) AIExecutor {
+	if policy.Retries <= 0 && policy.Timeout <= 0 {
+		return executor
+	}
+	return &RetryExecutor{Executor: executor, Policy: policy}
+}
+
+// Execute runs the wrapped executor until it succeeds, fails permanently or runs out of attempts.
+func (e *RetryExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	for attempt := 1; ; attempt++ {
+		output, err := e.attempt(ctx, prompt)
+		if err == nil {
+			return output, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		if attempt > e.Policy.Retries || !isRetryable(err) {
+			if attempt > 1 {
+				return "", fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+			return "", err
+		}
+
+		delay := e.backoff(attempt)
+		if e.OnRetry != nil {
+			e.OnRetry(attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (e *RetryExecutor) attempt(ctx context.Context, prompt string) (string, error) {
+	attemptCtx := ctx
+	if e.Policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, e.Policy.Timeout)
+		defer cancel()
+	}
+
+	output, err := e.Executor.Execute(attemptCtx, prompt)
+	if err != nil {
+		// Only the per-attempt deadline counts as a timeout; cancellation of ctx is final
+		if errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return "", fmt.Errorf("%w after %s", errAttemptTimeout, e.Policy.Timeout)
+		}
+		return "", err
+	}
+	if strings.TrimSpace(output) == "" {
+		return "", errEmptyResponse
+	}
+	return output, nil
+}
+
+// backoff returns the delay before the retry following attempt, with jitter
+// drawn uniformly from the upper half of the exponential delay.
+func (e *RetryExecutor) backoff(attempt int) time.Duration {
+	delay := e.Policy.BaseDelay
+	for i := 1; i < attempt && (e.Policy.MaxDelay <= 0 || delay < e.Policy.MaxDelay); i++ {
+		delay *= 2
+	}
+	if e.Policy.MaxDelay > 0 && delay > e.Policy.MaxDelay {
+		delay = e.Policy.MaxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(delay-half)+1)) // #nosec G404 - jitter does not need a secure source
+}
+
+// isRetryable reports whether err is likely transient: empty output, a timed-out
+// attempt, a rate limit, an HTTP 408/5xx response or a CLI failure mentioning rate
+// limits. Authentication failures and refusals are never retried.
+func isRetryable(err error) bool {
+	if errors.Is(err, errAuth) || errors.Is(err, errRefused) {
+		return false
+	}
+	if errors.Is(err, errEmptyResponse) || errors.Is(err, errAttemptTimeout) || errors.Is(err, errRateLimited) {
+		return true
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return rateLimitPattern.MatchString(err.Error())
+	}
+	return false
+}
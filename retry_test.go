@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryExecutor(t *testing.T) {
+	rateLimited := func() error {
+		err := exec.Command("sh", "-c", "exit 1").Run()
+		return errors.Join(errors.New("claude execution failed: Rate limit reached"), err)
+	}
+
+	tests := []struct {
+		name          string
+		responses     []string
+		errs          []error
+		retries       int
+		want          string
+		wantCalls     int
+		errorContains string
+	}{
+		{
+			name:      "succeeds first time",
+			responses: []string{"feat: a"},
+			errs:      []error{nil},
+			retries:   2,
+			want:      "feat: a",
+			wantCalls: 1,
+		},
+		{
+			name:      "retries empty output",
+			responses: []string{"", "feat: b"},
+			errs:      []error{nil, nil},
+			retries:   2,
+			want:      "feat: b",
+			wantCalls: 2,
+		},
+		{
+			name:      "retries rate limited exit",
+			responses: []string{"", "", "feat: c"},
+			errs:      []error{rateLimited(), &httpStatusError{StatusCode: http.StatusServiceUnavailable}, nil},
+			retries:   2,
+			want:      "feat: c",
+			wantCalls: 3,
+		},
+		{
+			name:          "does not retry permanent errors",
+			responses:     []string{""},
+			errs:          []error{&httpStatusError{StatusCode: http.StatusUnauthorized, Body: "invalid x-api-key"}},
+			retries:       2,
+			wantCalls:     1,
+			errorContains: "status 401",
+		},
+		{
+			name:          "gives up after retries",
+			responses:     []string{"", "", ""},
+			errs:          []error{nil, nil, nil},
+			retries:       2,
+			wantCalls:     3,
+			errorContains: "giving up after 3 attempts: empty response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var retried []int
+			executor := &RetryExecutor{
+				Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+					i := calls
+					calls++
+					return tt.responses[i], tt.errs[i]
+				}),
+				Policy:  RetryPolicy{Retries: tt.retries, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond},
+				OnRetry: func(attempt int, err error, delay time.Duration) { retried = append(retried, attempt) },
+			}
+
+			result, err := executor.Execute(context.Background(), "prompt")
+			if calls != tt.wantCalls {
+				t.Errorf("called %d times, want %d", calls, tt.wantCalls)
+			}
+			if len(retried) != max(0, min(tt.wantCalls-1, tt.retries)) && tt.errorContains == "" {
+				t.Errorf("OnRetry called for attempts %v", retried)
+			}
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Execute() error = %v, want error containing %s", err, tt.errorContains)
+				}
+				return
+			}
+			if err != nil || result != tt.want {
+				t.Errorf("Execute() = (%q, %v), want %q", result, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryExecutorAttemptTimeout(t *testing.T) {
+	calls := 0
+	executor := &RetryExecutor{
+		Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			calls++
+			if calls == 1 {
+				<-ctx.Done() // hang until the attempt deadline
+				return "", ctx.Err()
+			}
+			return "feat: after timeout", nil
+		}),
+		Policy: RetryPolicy{Retries: 1, Timeout: 20 * time.Millisecond, BaseDelay: time.Millisecond},
+	}
+
+	result, err := executor.Execute(context.Background(), "prompt")
+	if err != nil || result != "feat: after timeout" {
+		t.Errorf("Execute() = (%q, %v), want retry after timeout", result, err)
+	}
+}
+
+func TestRetryExecutorStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	executor := &RetryExecutor{
+		Executor: funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+			calls++
+			cancel()
+			return "", ctx.Err()
+		}),
+		Policy: RetryPolicy{Retries: 3, Timeout: time.Minute, BaseDelay: time.Millisecond},
+	}
+
+	if _, err := executor.Execute(ctx, "prompt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Execute() error = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("called %d times, want 1", calls)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	executor := &RetryExecutor{Policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}}
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := executor.backoff(tt.attempt); d < tt.min || d > tt.max {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	inner := &MockAIExecutor{}
+	if got := withRetry(inner, RetryPolicy{}); got != inner {
+		t.Errorf("withRetry() with a disabled policy should return the executor unchanged")
+	}
+	if _, ok := withRetry(inner, defaultRetryPolicy()).(*RetryExecutor); !ok {
+		t.Errorf("withRetry() should wrap the executor")
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shivase/gcauto/internal/validator"
+)
+
+// defaultTerminalWidth is the width of the side-by-side view when $COLUMNS is not set.
+const defaultTerminalWidth = 120
+
+// errRewordAborted is returned when the user quits before the history is rewritten.
+var errRewordAborted = errors.New("reword aborted")
+
+// rewordCommit is a commit of the range being reworded.
+type rewordCommit struct {
+	Hash    string
+	Tree    string
+	Parents []string
+	Message string
+	// NewMessage is the accepted message, empty when the message is kept.
+	NewMessage string
+}
+
+// runGit runs git with args and input on stdin and returns its output without the
+// trailing newlines.
+func runGit(ctx context.Context, input string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	return gitOutput(cmd)
+}
+
+// gitOutput runs a git command and returns its output without the trailing newlines.
+// The error includes what git printed on stderr.
+func gitOutput(cmd *exec.Cmd) (string, error) {
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", cmd.Args[1], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", cmd.Args[1], err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// resolveRewordRange parses a "<base>..<head>" range, where a lone "<base>" means
+// "<base>..HEAD", and returns the ref to update and the commits of the range, oldest
+// first. head must be HEAD or a branch so that the rewritten history has a ref to
+// point to, and the range must not contain merge commits.
+func resolveRewordRange(ctx context.Context, spec string) (string, []rewordCommit, error) {
+	base, head, found := strings.Cut(spec, "..")
+	if !found || head == "" {
+		head = "HEAD"
+	}
+	if base == "" {
+		return "", nil, fmt.Errorf("invalid range %q (expected <base>..<head>)", spec)
+	}
+
+	ref, err := runGit(ctx, "", "rev-parse", "--symbolic-full-name", head)
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+	case ref == "HEAD":
+		// A detached HEAD is updated directly
+	default:
+		return "", nil, fmt.Errorf("%s is not a branch; give a branch or HEAD as the end of the range", head)
+	}
+
+	merges, err := runGit(ctx, "", "rev-list", "--min-parents=2", base+".."+head)
+	if err != nil {
+		return "", nil, err
+	}
+	if merges != "" {
+		return "", nil, errors.New("the range contains merge commits, which cannot be reworded")
+	}
+
+	// One record per commit: hash, tree, parents and the raw message, NUL separated
+	records, err := runGit(ctx, "", "log", "--reverse", "--format=%H%x00%T%x00%P%x00%B%x00", base+".."+head)
+	if err != nil {
+		return "", nil, err
+	}
+	fields := strings.Split(records, "\x00")
+	var commits []rewordCommit
+	for i := 0; i+3 < len(fields); i += 4 {
+		commits = append(commits, rewordCommit{
+			Hash:    strings.TrimSpace(fields[i]),
+			Tree:    fields[i+1],
+			Parents: strings.Fields(fields[i+2]),
+			Message: strings.TrimSpace(fields[i+3]),
+		})
+	}
+	if len(commits) == 0 {
+		return "", nil, fmt.Errorf("no commits in %s..%s", base, head)
+	}
+	return ref, commits, nil
+}
+
+// commitChanges returns the diff, file list and stat of a single commit.
+func commitChanges(ctx context.Context, hash string) (diff, fileList, stat string, err error) {
+	if diff, err = runGit(ctx, "", "show", "--format=", "--patch", hash); err != nil {
+		return "", "", "", err
+	}
+	if fileList, err = runGit(ctx, "", "show", "--format=", "--name-only", hash); err != nil {
+		return "", "", "", err
+	}
+	if stat, err = runGit(ctx, "", "show", "--format=", "--stat", hash); err != nil {
+		return "", "", "", err
+	}
+	return diff + "\n", strings.TrimSpace(fileList), strings.TrimSpace(stat), nil
+}
+
+// rewriteCommits recreates the commits from the first reworded one onwards with
+// commit-tree, keeping their trees, authors and author dates, and returns the new
+// hash of the last commit. Commits before the first reworded one are kept as they are.
+func rewriteCommits(ctx context.Context, commits []rewordCommit) (string, error) {
+	rewritten := map[string]string{}
+	head := commits[len(commits)-1].Hash
+	for _, c := range commits {
+		parents := make([]string, len(c.Parents))
+		changed := c.NewMessage != ""
+		for i, parent := range c.Parents {
+			parents[i] = parent
+			if hash, ok := rewritten[parent]; ok {
+				parents[i] = hash
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		author, err := runGit(ctx, "", "log", "-1", "--format=%an%x00%ae%x00%ad", "--date=raw", c.Hash)
+		if err != nil {
+			return "", err
+		}
+		parts := strings.SplitN(author, "\x00", 3)
+		if len(parts) != 3 {
+			return "", fmt.Errorf("failed to read the author of %s", c.Hash)
+		}
+
+		message := c.Message
+		if c.NewMessage != "" {
+			message = c.NewMessage
+		}
+		args := []string{"commit-tree", c.Tree}
+		for _, parent := range parents {
+			args = append(args, "-p", parent)
+		}
+		cmd := exec.CommandContext(ctx, "git", append(args, "-F", "-")...)
+		cmd.Stdin = strings.NewReader(message + "\n")
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME="+parts[0], "GIT_AUTHOR_EMAIL="+parts[1], "GIT_AUTHOR_DATE="+parts[2])
+		hash, err := gitOutput(cmd)
+		if err != nil {
+			return "", fmt.Errorf("failed to rewrite %s: %w", shortHash(c.Hash), err)
+		}
+		rewritten[c.Hash] = hash
+		head = rewritten[c.Hash]
+	}
+	return head, nil
+}
+
+// runReword generates a new message for every commit of the range, asks which ones
+// to use, and rewrites the history for the accepted ones. It returns the exit code.
+func runReword(ctx context.Context, cfg *Config, spec string) int {
+	ref, commits, err := resolveRewordRange(ctx, spec)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+
+	opts, _, err := newPromptOptions(ctx, cfg)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	if err := cfg.Scope.Validate(); err != nil {
+		fmt.Printf("❌ Error: Invalid scope rule: %v\n", err)
+		return 1
+	}
+	if err := cfg.Diff.Validate(); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	ticketRules, err := compileTicketRules(cfg.Tickets)
+	if err != nil {
+		fmt.Printf("❌ Error: Invalid ticket rule: %v\n", err)
+		return 1
+	}
+	lintRules, _, err := loadLintRules(ctx, cfg)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	ignored, _, err := loadIgnoreRules(ctx)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	secrets, err := newSecretScanner(cfg.Secrets)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	executor, err := newExecutor(strings.Join(cfg.Models, ","), cfg, func(output string) error { return acceptCommitMessage(output, lintRules) })
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✍️ Rewording %d commit(s) of %s...\n", len(commits), spec)
+	reader := bufio.NewReader(os.Stdin)
+	for i := range commits {
+		c := &commits[i]
+		diff, fileList, stat, err := commitChanges(ctx, c.Hash)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return 1
+		}
+		diff, _ = ignored.filterDiff(diff)
+		redacted, findings := secrets.redact(diff)
+		if len(findings) > 0 && cfg.Secrets.Policy == secretPolicyBlock {
+			fmt.Printf("⚠️ Warning: Keeping the message of %s, its diff contains %d possible secret(s)\n", shortHash(c.Hash), len(findings))
+			continue
+		}
+
+		commitOpts := opts
+		commitOpts.Examples = loadExamples(ctx, cfg.Examples, fileList)
+		commitOpts.Scope = inferScope(ctx, cfg.Scope, fileList)
+		// finalize applies the same deterministic fixes as the commit flow
+		finalize := func(message string) string {
+			if commitOpts.Scope != "" {
+				message = rewriteScope(message, commitOpts.Scope)
+			}
+			return applyTicketRules(message, commitOpts.Branch, ticketRules)
+		}
+		generate := func() (string, error) {
+			message, err := generateCommitMessage(ctx, executor, redacted, fileList, stat, commitOpts)
+			if err != nil || message == "" {
+				return "", err
+			}
+			return repairMessage(finalize(message), lintRules, cfg.Lint.RepairAttempts, func(previous string, violations []validator.Violation) (string, error) {
+				revised, err := repairCommitMessage(ctx, executor, redacted, fileList, stat, previous, violations, commitOpts)
+				if err != nil {
+					return "", err
+				}
+				return finalize(revised), nil
+			}), nil
+		}
+
+		fmt.Printf("\n🔎 [%d/%d] %s %s\n", i+1, len(commits), shortHash(c.Hash), firstLine(c.Message))
+		if len(findings) > 0 {
+			fmt.Printf("🔒 Redacted %d possible secret(s) from the prompt:\n", len(findings))
+			printSecretFindings(findings)
+		}
+		message, err := generate()
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Println("\n⏹️ Interrupted. Nothing was rewritten.")
+				return 1
+			}
+			fmt.Printf("⚠️ Warning: Keeping the message, failed to generate a new one: %v\n", err)
+			continue
+		}
+		if message == "" {
+			fmt.Println("⚠️ Warning: Keeping the message, the AI returned no usable message")
+			continue
+		}
+
+		accepted, err := confirmReword(ctx, reader, c.Message, message, cfg.Behavior.AutoConfirm, lintRules, generate)
+		if errors.Is(err, errRewordAborted) {
+			fmt.Println("\n⏹️ Reword canceled. Nothing was rewritten.")
+			return 0
+		}
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return 1
+		}
+		c.NewMessage = accepted
+	}
+
+	reworded := 0
+	for _, c := range commits {
+		if c.NewMessage != "" {
+			reworded++
+		}
+	}
+	if reworded == 0 {
+		fmt.Println("\n✅ No commits were reworded.")
+		return 0
+	}
+
+	oldHead := commits[len(commits)-1].Hash
+	newHead, err := rewriteCommits(ctx, commits)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	if _, err := runGit(ctx, "", "update-ref", "-m", "gcauto reword "+spec, ref, newHead, oldHead); err != nil {
+		fmt.Printf("❌ Error: Failed to update %s: %v\n", ref, err)
+		return 1
+	}
+	fmt.Printf("\n✅ Reworded %d of %d commit(s) on %s\n", reworded, len(commits), strings.TrimPrefix(ref, "refs/heads/"))
+	fmt.Printf("   To undo: git update-ref %s %s\n", ref, oldHead)
+	return 0
+}
+
+// confirmReword shows the old and new message side by side and asks whether to use
+// the new one. It returns the accepted message, or "" to keep the old one.
+func confirmReword(ctx context.Context, reader *bufio.Reader, old, message string, autoConfirm bool, rules *validator.Config, generate func() (string, error)) (string, error) {
+	for {
+		printSideBySide("Current message", old, "New message", message, terminalWidth())
+		printViolations(message, rules)
+		if autoConfirm {
+			return message, nil
+		}
+
+		fmt.Print("\nUse the new message? [y/N/e/r/q]: ")
+		fmt.Print("\n  y/yes - Use the new message")
+		fmt.Print("\n  n/no  - Keep the current message")
+		fmt.Print("\n  e/edit - Edit the new message in your editor")
+		fmt.Print("\n  r/regenerate - Generate a fresh message")
+		fmt.Print("\n  q/quit - Stop without rewriting anything")
+		fmt.Print("\n\nYour choice: ")
+
+		response, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || response == "") {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+		switch strings.TrimSpace(strings.ToLower(response)) {
+		case "y", "yes":
+			return message, nil
+		case "n", "no", "":
+			return "", nil
+		case "q", "quit":
+			return "", errRewordAborted
+		case "e", "edit":
+			edited, editErr := editMessageInEditor(ctx, message)
+			if editErr != nil || edited == "" {
+				fmt.Println("\n⚠️ Keeping the generated message...")
+				continue
+			}
+			message = edited
+		case "r", "regenerate":
+			fmt.Println("\n🔄 Regenerating commit message...")
+			regenerated, genErr := generate()
+			if genErr != nil || regenerated == "" {
+				fmt.Printf("\n⚠️ Failed to regenerate, keeping the previous message: %v\n", genErr)
+				continue
+			}
+			message = regenerated
+		default:
+			fmt.Println("\n⚠️ Invalid choice. Please enter y, n, e, r, or q.")
+		}
+	}
+}
+
+// printSideBySide prints two messages in columns, wrapping long lines.
+func printSideBySide(leftTitle, left, rightTitle, right string, width int) {
+	column := max((width-3)/2, 20)
+	leftLines := wrapLines(left, column)
+	rightLines := wrapLines(right, column)
+
+	rule := strings.Repeat("=", column)
+	fmt.Println(rule + "=|=" + rule)
+	fmt.Println(padDisplay(leftTitle, column) + " | " + rightTitle)
+	fmt.Println(strings.Repeat("-", column) + "-|-" + strings.Repeat("-", column))
+	for i := 0; i < max(len(leftLines), len(rightLines)); i++ {
+		var l, r string
+		if i < len(leftLines) {
+			l = leftLines[i]
+		}
+		if i < len(rightLines) {
+			r = rightLines[i]
+		}
+		fmt.Println(strings.TrimRight(padDisplay(l, column)+" | "+r, " "))
+	}
+	fmt.Println(rule + "=|=" + rule)
+}
+
+// wrapLines splits s into lines of at most width display columns.
+func wrapLines(s string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		for displayWidth(line) > width {
+			cut, used := 0, 0
+			for i, r := range line {
+				if w := runeWidth(r); used+w > width {
+					cut = i
+					break
+				} else {
+					used += w
+				}
+			}
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// padDisplay pads s with spaces to width display columns.
+func padDisplay(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-displayWidth(s), 0))
+}
+
+// displayWidth returns the number of terminal columns s takes.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth returns 2 for the wide characters of CJK text and 1 otherwise.
+func runeWidth(r rune) int {
+	if r >= utf8.RuneSelf && (unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60) || (r >= 0xffe0 && r <= 0xffe6)) {
+		return 2
+	}
+	return 1
+}
+
+// terminalWidth returns $COLUMNS, or defaultTerminalWidth when it is not set.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultTerminalWidth
+}
+
+// shortHash abbreviates a commit hash for display.
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// packageManifests mark the root directory of a package in a monorepo.
+var packageManifests = []string{"go.mod", "package.json", "Cargo.toml"}
+
+// ScopeRule maps staged paths matching Glob to Scope.
+type ScopeRule struct {
+	// Glob is matched against the path from the repository root. "*" matches within
+	// one path segment and "**" matches any number of segments.
+	Glob  string `toml:"glob"`
+	Scope string `toml:"scope"`
+}
+
+// ScopeConfig controls how the commit scope is derived from the staged paths.
+type ScopeConfig struct {
+	// Rules are tried in order; the first match decides the scope of a path.
+	Rules []ScopeRule `toml:"rules"`
+	// Packages uses the directory name of the nearest package (a directory below the
+	// repository root containing go.mod, package.json or Cargo.toml) for paths no rule matches.
+	Packages bool `toml:"packages"`
+}
+
+// Validate checks that every rule has a valid glob and a scope.
+func (c ScopeConfig) Validate() error {
+	for i, rule := range c.Rules {
+		if rule.Glob == "" || rule.Scope == "" {
+			return fmt.Errorf("scope.rules[%d]: glob and scope are required", i)
+		}
+		if _, err := path.Match(strings.ReplaceAll(rule.Glob, "**", "*"), ""); err != nil {
+			return fmt.Errorf("scope.rules[%d]: invalid glob %q: %w", i, rule.Glob, err)
+		}
+	}
+	return nil
+}
+
+// inferScope resolves the scope of the staged files in the current repository.
+func inferScope(ctx context.Context, settings ScopeConfig, fileList string) string {
+	// Package detection needs the repository root; rules work without it
+	root, _ := gitRepoRoot(ctx)
+	return resolveScope(root, settings, fileList)
+}
+
+// resolveScope returns the scope shared by the staged files, or "" when the files map
+// to several scopes or to none. Files no rule or package covers do not count, so that
+// e.g. a root lockfile does not prevent the scope of the package that changed.
+func resolveScope(root string, settings ScopeConfig, fileList string) string {
+	if len(settings.Rules) == 0 && !settings.Packages {
+		return ""
+	}
+
+	scope := ""
+	packages := map[string]string{}
+	for _, file := range strings.Split(fileList, "\n") {
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		fileScope := scopeForPath(root, settings, file, packages)
+		if fileScope == "" {
+			continue
+		}
+		if scope != "" && fileScope != scope {
+			return ""
+		}
+		scope = fileScope
+	}
+	return scope
+}
+
+// scopeForPath returns the scope of a single path. packages caches the package
+// directory lookups by directory.
+func scopeForPath(root string, settings ScopeConfig, file string, packages map[string]string) string {
+	for _, rule := range settings.Rules {
+		if matchPathGlob(rule.Glob, file) {
+			return rule.Scope
+		}
+	}
+	if !settings.Packages || root == "" {
+		return ""
+	}
+
+	// Walk up from the file's directory, stopping before the repository root
+	var visited []string
+	scope := ""
+	for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if cached, ok := packages[dir]; ok {
+			scope = cached
+			break
+		}
+		visited = append(visited, dir)
+		if isPackageDir(filepath.Join(root, filepath.FromSlash(dir))) {
+			scope = path.Base(dir)
+			break
+		}
+	}
+	for _, dir := range visited {
+		packages[dir] = scope
+	}
+	return scope
+}
+
+func isPackageDir(dir string) bool {
+	for _, manifest := range packageManifests {
+		if _, err := os.Stat(filepath.Join(dir, manifest)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathGlob reports whether the slash-separated name matches pattern, where "**"
+// matches zero or more whole path segments.
+func matchPathGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// rewriteScope replaces the scope of a conventional subject with scope, adding one
+// when the subject has none. Other messages are returned unchanged.
+func rewriteScope(message, scope string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+	m := conventionalHeaderPattern.FindStringSubmatch(subject)
+	if m == nil || m[2] == scope {
+		return message
+	}
+	subject = fmt.Sprintf("%s(%s)%s: %s", m[1], scope, m[3], m[4])
+
+	if hasRest {
+		return subject + "\n" + rest
+	}
+	return subject
+}
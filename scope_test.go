@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchPathGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"cmd/api/**", "cmd/api/main.go", true},
+		{"cmd/api/**", "cmd/api/handlers/users.go", true},
+		{"cmd/api/**", "cmd/web/main.go", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "docs/guide/intro.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**", "anything/at/all", true},
+	}
+	for _, tt := range tests {
+		if got := matchPathGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchPathGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveScope(t *testing.T) {
+	root := t.TempDir()
+	for _, manifest := range []string{"packages/web/package.json", "services/api/go.mod", "crates/core/Cargo.toml", "go.mod"} {
+		path := filepath.Join(root, filepath.FromSlash(manifest))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings := ScopeConfig{
+		Rules:    []ScopeRule{{Glob: "docs/**", Scope: "docs"}, {Glob: "services/api/openapi/**", Scope: "openapi"}},
+		Packages: true,
+	}
+	tests := []struct {
+		name     string
+		settings ScopeConfig
+		files    string
+		want     string
+	}{
+		{"rule", settings, "docs/a.md\ndocs/b/c.md", "docs"},
+		{"rule wins over package", settings, "services/api/openapi/spec.yaml", "openapi"},
+		{"package", settings, "packages/web/src/app.tsx\npackages/web/package.json", "web"},
+		{"go module", settings, "services/api/internal/db/db.go", "api"},
+		{"cargo crate", settings, "crates/core/src/lib.rs", "core"},
+		{"unscoped files are ignored", settings, "pnpm-lock.yaml\npackages/web/index.ts", "web"},
+		{"several scopes", settings, "packages/web/index.ts\nservices/api/main.go", ""},
+		{"root module is not a package", settings, "main.go\ninternal/x/x.go", ""},
+		{"packages disabled", ScopeConfig{}, "packages/web/index.ts", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveScope(root, tt.settings, tt.files); got != tt.want {
+				t.Errorf("resolveScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeConfigValidate(t *testing.T) {
+	valid := ScopeConfig{Rules: []ScopeRule{{Glob: "cmd/**", Scope: "cmd"}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error = %v", err)
+	}
+	for _, rule := range []ScopeRule{{Glob: "cmd/**"}, {Scope: "cmd"}, {Glob: "cmd/[", Scope: "cmd"}} {
+		if err := (ScopeConfig{Rules: []ScopeRule{rule}}).Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", rule)
+		}
+	}
+}
+
+func TestRewriteScope(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"feat(server): add x\n\nbody", "feat(api): add x\n\nbody"},
+		{"fix!: crash", "fix(api)!: crash"},
+		{"feat(api): unchanged", "feat(api): unchanged"},
+		{"Update README", "Update README"},
+	}
+	for _, tt := range tests {
+		if got := rewriteScope(tt.message, "api"); got != tt.want {
+			t.Errorf("rewriteScope(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+
+	prompt, err := buildCommitPrompt("diff", "api/main.go", "stat", promptOptions{Language: "en", Scope: "api", Scopes: []string{"web"}})
+	if err != nil {
+		t.Fatalf("buildCommitPrompt() unexpected error = %v", err)
+	}
+	if !strings.Contains(prompt, `The scope must be "api"`) || strings.Contains(prompt, "use one of") {
+		t.Errorf("prompt should require the inferred scope, got:\n%s", prompt)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Policies for secrets found in the diff sent to the AI.
+const (
+	secretPolicyWarn  = "warn"
+	secretPolicyBlock = "block"
+	secretPolicyOff   = "off"
+)
+
+// Defaults of the high-entropy string detection.
+const (
+	defaultSecretMinEntropy = 4.2
+	secretEntropyMinLength  = 24
+)
+
+// ruleHighEntropy is the rule name of strings reported by the entropy detection.
+const ruleHighEntropy = "high-entropy-string"
+
+// rulePrivateKey is the rule name of private key blocks.
+const rulePrivateKey = "private-key"
+
+// SecretsConfig configures the detection of secrets in the staged diff.
+type SecretsConfig struct {
+	// Policy is "warn" (default) to redact secrets and continue, "block" to stop
+	// before anything is sent to the AI, or "off" to disable the detection.
+	Policy string `toml:"policy"`
+	// Patterns are additional regular expressions by rule name. When a pattern has a
+	// capture group, only the first group is redacted.
+	Patterns map[string]string `toml:"patterns"`
+	// MinEntropy is the Shannon entropy, in bits per character, above which long
+	// random-looking strings are redacted; zero disables the check.
+	MinEntropy float64 `toml:"min_entropy"`
+	// BlockCommits refuses to commit staged changes that add high-confidence secrets,
+	// unless --allow-secret is given.
+	BlockCommits bool `toml:"block_commits"`
+}
+
+// Validate checks the policy. Patterns are checked when they are compiled.
+func (c SecretsConfig) Validate() error {
+	switch c.Policy {
+	case "", secretPolicyWarn, secretPolicyBlock, secretPolicyOff:
+		return nil
+	default:
+		return fmt.Errorf("invalid secrets.policy %q (expected %s, %s or %s)", c.Policy, secretPolicyWarn, secretPolicyBlock, secretPolicyOff)
+	}
+}
+
+// secretRule detects one kind of secret. highConfidence rules rarely match anything
+// else, unlike heuristics such as the entropy check.
+type secretRule struct {
+	name           string
+	pattern        *regexp.Regexp
+	highConfidence bool
+}
+
+// builtinSecretRules detect well-known credential formats.
+var builtinSecretRules = []secretRule{
+	{"aws-access-key-id", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`), true},
+	{"aws-secret-access-key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`), true},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`), true},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`), true},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), true},
+	{"generic-secret", regexp.MustCompile(`(?i)(?:password|passwd|secret|api_?key|access_?token|auth_?token)["']?\s*[:=]\s*["']([^"'\s]{8,})["']`), false},
+}
+
+var (
+	privateKeyBeginPattern  = regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEndPattern    = regexp.MustCompile(`-----END (?:[A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)
+	entropyCandidatePattern = regexp.MustCompile(`[A-Za-z0-9+/=_-]{` + strconv.Itoa(secretEntropyMinLength) + `,}`)
+	hunkHeaderPattern       = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+)
+
+// secretFinding is a possible secret in a diff.
+type secretFinding struct {
+	Rule string
+	File string
+	// Line is the line number in the new file, or in the old file for removed lines.
+	Line int
+	// Added reports whether the secret is on a line the diff adds rather than on a
+	// removed or context line.
+	Added          bool
+	HighConfidence bool
+}
+
+func (f secretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Rule)
+}
+
+// secretScanner finds and redacts secrets in diffs.
+type secretScanner struct {
+	rules      []secretRule
+	minEntropy float64
+}
+
+// newSecretScanner compiles the configured patterns. It returns nil when the policy
+// is "off"; a nil scanner finds nothing.
+func newSecretScanner(cfg SecretsConfig) (*secretScanner, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Policy == secretPolicyOff {
+		return nil, nil
+	}
+
+	rules := append([]secretRule(nil), builtinSecretRules...)
+	names := make([]string, 0, len(cfg.Patterns))
+	for name := range cfg.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pattern, err := regexp.Compile(cfg.Patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("secrets.patterns.%s: invalid pattern: %w", name, err)
+		}
+		rules = append(rules, secretRule{name: name, pattern: pattern, highConfidence: true})
+	}
+	return &secretScanner{rules: rules, minEntropy: cfg.MinEntropy}, nil
+}
+
+// redact replaces the secrets in the changed and context lines of diff with
+// placeholders such as "[REDACTED:github-token]", and reports where they were.
+func (s *secretScanner) redact(diff string) (string, []secretFinding) {
+	if s == nil {
+		return diff, nil
+	}
+
+	var findings []secretFinding
+	var out strings.Builder
+	var file, kind string
+	inHunk, inKey := false, false
+	oldLine, newLine := 0, 0
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inHunk, inKey = false, false
+			if i := strings.LastIndex(line, " b/"); i != -1 {
+				file = strings.TrimSpace(line[i+len(" b/"):])
+			}
+			kind = classifyDiffFile(file, "")
+		case !inHunk && strings.HasPrefix(line, "+++ b/"):
+			file = strings.TrimSpace(strings.TrimPrefix(line, "+++ b/"))
+			kind = classifyDiffFile(file, "")
+		case strings.HasPrefix(line, "@@"):
+			if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+				oldLine, _ = strconv.Atoi(m[1])
+				newLine, _ = strconv.Atoi(m[2])
+				inHunk = true
+			}
+		case inHunk && line != "" && strings.ContainsRune("+- ", rune(line[0])):
+			finding := secretFinding{File: file, Line: newLine, Added: line[0] == '+'}
+			if line[0] == '-' {
+				finding.Line = oldLine
+				oldLine++
+			} else {
+				if line[0] == ' ' {
+					oldLine++
+				}
+				newLine++
+			}
+
+			content, rules := s.redactLine(line[1:], kind, &inKey)
+			for _, rule := range rules {
+				f := finding
+				f.Rule, f.HighConfidence = rule.name, rule.highConfidence
+				findings = append(findings, f)
+			}
+			line = line[:1] + content
+		}
+		out.WriteString(line)
+	}
+	return out.String(), findings
+}
+
+// redactLine redacts one line of content and returns the rules that matched. inKey
+// tracks whether the line is inside a private key block.
+func (s *secretScanner) redactLine(content, kind string, inKey *bool) (string, []secretRule) {
+	newline := ""
+	if strings.HasSuffix(content, "\n") {
+		content, newline = strings.TrimSuffix(content, "\n"), "\n"
+	}
+
+	keyRule := secretRule{name: rulePrivateKey, highConfidence: true}
+	switch {
+	case privateKeyBeginPattern.MatchString(content):
+		*inKey = !privateKeyEndPattern.MatchString(content)
+		return redactionPlaceholder(rulePrivateKey) + newline, []secretRule{keyRule}
+	case *inKey:
+		*inKey = !privateKeyEndPattern.MatchString(content)
+		return redactionPlaceholder(rulePrivateKey) + newline, nil
+	}
+
+	var matched []secretRule
+	for _, rule := range s.rules {
+		var n int
+		content, n = redactMatches(content, rule)
+		for i := 0; i < n; i++ {
+			matched = append(matched, rule)
+		}
+	}
+
+	// Lockfiles, generated and vendored files are full of checksums and encoded data
+	if s.minEntropy > 0 && kind == "" {
+		entropyRule := secretRule{name: ruleHighEntropy}
+		content = entropyCandidatePattern.ReplaceAllStringFunc(content, func(candidate string) string {
+			if !looksRandom(candidate, s.minEntropy) {
+				return candidate
+			}
+			matched = append(matched, entropyRule)
+			return redactionPlaceholder(ruleHighEntropy)
+		})
+	}
+	return content + newline, matched
+}
+
+// redactMatches replaces the matches of rule in content, or their first capture
+// group when the pattern has one, and returns the number of replacements.
+func redactMatches(content string, rule secretRule) (string, int) {
+	matches := rule.pattern.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content, 0
+	}
+	var b strings.Builder
+	last, n := 0, 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if strings.HasPrefix(content[start:], "[REDACTED:") {
+			continue
+		}
+		b.WriteString(content[last:start])
+		b.WriteString(redactionPlaceholder(rule.name))
+		last = end
+		n++
+	}
+	b.WriteString(content[last:])
+	return b.String(), n
+}
+
+// redactionPlaceholder is the text a secret is replaced with.
+func redactionPlaceholder(rule string) string {
+	return "[REDACTED:" + rule + "]"
+}
+
+// looksRandom reports whether s mixes upper case letters, lower case letters and
+// digits with a Shannon entropy of at least minEntropy bits per character, like API
+// keys and passwords do but identifiers and hex hashes do not.
+func looksRandom(s string, minEntropy float64) bool {
+	var upper, lower, digit bool
+	counts := map[rune]int{}
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+		counts[r]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minEntropy
+}
+
+// printSecretFindings lists where secrets were found.
+func printSecretFindings(findings []secretFinding) {
+	for _, finding := range findings {
+		fmt.Printf("   - %s\n", finding)
+	}
+}
+
+// secretAllowlistFile lists known false positives at the repository root, one
+// "<path>[:<rule>]" entry per line. The path uses the gitignore syntax, and an entry
+// without a rule allows every rule in the matching files.
+const secretAllowlistFile = ".gcauto-secret-allowlist"
+
+// secretAllowEntry is one entry of the allowlist file.
+type secretAllowEntry struct {
+	path ignoreRule
+	rule string
+}
+
+// secretAllowlist is the parsed allowlist file.
+type secretAllowlist []secretAllowEntry
+
+// parseSecretAllowlist parses the allowlist file. Blank lines and "#" comments are skipped.
+func parseSecretAllowlist(src string) secretAllowlist {
+	var entries secretAllowlist
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		path, rule, _ := strings.Cut(line, ":")
+		rules := parseIgnoreRules(path)
+		if len(rules) == 0 {
+			continue
+		}
+		entries = append(entries, secretAllowEntry{path: rules[0], rule: strings.TrimSpace(rule)})
+	}
+	return entries
+}
+
+// loadSecretAllowlist reads the allowlist file of the repository, if there is one.
+func loadSecretAllowlist(ctx context.Context) (secretAllowlist, error) {
+	root, err := gitRepoRoot(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	src, err := os.ReadFile(filepath.Join(root, secretAllowlistFile)) // #nosec G304 - the allowlist is at a fixed location
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret allowlist: %w", err)
+	}
+	return parseSecretAllowlist(string(src)), nil
+}
+
+// allows reports whether finding is a known false positive.
+func (a secretAllowlist) allows(finding secretFinding) bool {
+	for _, entry := range a {
+		if (entry.rule == "" || entry.rule == finding.Rule) && entry.path.matches(finding.File) {
+			return true
+		}
+	}
+	return false
+}
+
+// stagedSecrets returns the high-confidence secrets that diff adds and the allowlist
+// of the repository does not cover. Commits are refused while there are any.
+func stagedSecrets(ctx context.Context, diff string, settings SecretsConfig) ([]secretFinding, error) {
+	// The policy and the entropy check only concern what is sent to the AI
+	scanner, err := newSecretScanner(SecretsConfig{Patterns: settings.Patterns})
+	if err != nil {
+		return nil, err
+	}
+	allowlist, err := loadSecretAllowlist(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, findings := scanner.redact(diff)
+	var blocking []secretFinding
+	for _, finding := range findings {
+		if finding.HighConfidence && finding.Added && !allowlist.allows(finding) {
+			blocking = append(blocking, finding)
+		}
+	}
+	return blocking, nil
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shivase/gcauto/internal/validator"
+)
+
+// splitGroup is one commit of a split plan.
+type splitGroup struct {
+	Message string   `json:"message"`
+	Files   []string `json:"files"`
+}
+
+// splitSession holds what the split flow needs to plan the commits and write their
+// messages.
+type splitSession struct {
+	executor AIExecutor
+	// model is the model specification the plan is requested from.
+	model       string
+	cfg         *Config
+	opts        promptOptions
+	ticketRules []*ticketRule
+	lintRules   *validator.Config
+	// diff is the staged diff without ignored files and with secrets redacted.
+	diff string
+	stat string
+	// paths are the staged paths, see stagedPaths.
+	paths []string
+}
+
+// stagedPaths returns the staged paths. Renames are listed as the deleted and the
+// added path so that the plan accounts for both sides.
+func stagedPaths(ctx context.Context) ([]string, error) {
+	output, err := runGit(ctx, "", "diff", "--staged", "--name-only", "--no-renames", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range strings.Split(output, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// buildSplitPrompt renders the prompt asking for a commit plan of the changes.
+func buildSplitPrompt(diff, fileList, stat string, opts promptOptions) (string, error) {
+	text := promptTextFor(opts.Language)
+	var prompt strings.Builder
+	if err := text.split.Execute(&prompt, newPromptData(diff, fileList, stat, opts)); err != nil {
+		return "", fmt.Errorf("failed to render split prompt: %w", err)
+	}
+	if instructions := strings.TrimSpace(opts.Instructions); instructions != "" {
+		prompt.WriteString(text.instructions + instructions)
+	}
+	return prompt.String(), nil
+}
+
+// parseSplitPlan reads the commit plan the AI returned for paths. Files the plan does
+// not know are dropped and files listed twice stay in their first group. Staged files
+// the plan leaves out are added to the last group and returned so that the user can be
+// told about them.
+func parseSplitPlan(raw string, paths []string) ([]splitGroup, []string, error) {
+	start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}")
+	if start == -1 || end < start {
+		return nil, nil, errors.New("the AI did not return a commit plan")
+	}
+	var plan struct {
+		Commits []splitGroup `json:"commits"`
+	}
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &plan); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the commit plan: %w", err)
+	}
+
+	staged := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		staged[path] = true
+	}
+	assigned := make(map[string]bool, len(paths))
+	var groups []splitGroup
+	for _, g := range plan.Commits {
+		message := extractCommitMessage(strings.TrimSpace(g.Message))
+		if message == "" {
+			continue
+		}
+		var files []string
+		for _, path := range g.Files {
+			path = strings.TrimPrefix(strings.TrimSpace(path), "./")
+			if staged[path] && !assigned[path] {
+				assigned[path] = true
+				files = append(files, path)
+			}
+		}
+		if len(files) > 0 {
+			groups = append(groups, splitGroup{Message: message, Files: files})
+		}
+	}
+	if len(groups) == 0 {
+		return nil, nil, errors.New("the commit plan contains none of the staged files")
+	}
+
+	var unassigned []string
+	for _, path := range paths {
+		if !assigned[path] {
+			unassigned = append(unassigned, path)
+		}
+	}
+	last := &groups[len(groups)-1]
+	last.Files = append(last.Files, unassigned...)
+	return groups, unassigned, nil
+}
+
+// filterDiffFiles returns the part of diff that changes one of files.
+func filterDiffFiles(diff string, files []string) string {
+	keep := make(map[string]bool, len(files))
+	for _, path := range files {
+		keep[path] = true
+	}
+	var b strings.Builder
+	for _, file := range splitDiffFiles(diff) {
+		if keep[parseDiffFile(file).Path] {
+			b.WriteString(file)
+		}
+	}
+	return b.String()
+}
+
+// plan asks the AI how to split the staged changes.
+func (s *splitSession) plan(ctx context.Context) ([]splitGroup, error) {
+	prompt, err := buildSplitPrompt(s.diff, strings.Join(s.paths, "\n"), s.stat, s.opts)
+	if err != nil {
+		return nil, err
+	}
+	// Only a usable plan counts as a successful response
+	planner, err := newExecutor(s.model, s.cfg, func(output string) error {
+		_, _, err := parseSplitPlan(output, s.paths)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	raw, err := planner.Execute(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	reportBackends(planner)
+	groups, unassigned, err := parseSplitPlan(raw, s.paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(unassigned) > 0 {
+		fmt.Printf("⚠️ Warning: The plan left out %d file(s), adding them to the last commit: %s\n", len(unassigned), strings.Join(unassigned, ", "))
+	}
+	for i := range groups {
+		groups[i].Message = s.finish(ctx, groups[i])
+	}
+	return groups, nil
+}
+
+// describe asks the AI for a fresh message for the files of a group, e.g. after the
+// user moved files between groups.
+func (s *splitSession) describe(ctx context.Context, g splitGroup) (string, error) {
+	opts := s.groupOptions(ctx, g)
+	message, err := generateCommitMessage(ctx, s.executor, filterDiffFiles(s.diff, g.Files), strings.Join(g.Files, "\n"), "", opts)
+	if err != nil || message == "" {
+		return "", err
+	}
+	return s.finish(ctx, splitGroup{Message: message, Files: g.Files}), nil
+}
+
+// groupOptions returns the prompt options for a message of one group, with the scope
+// of its files instead of the scope of all staged files.
+func (s *splitSession) groupOptions(ctx context.Context, g splitGroup) promptOptions {
+	opts := s.opts
+	opts.Summaries = nil
+	opts.Scope = inferScope(ctx, s.cfg.Scope, strings.Join(g.Files, "\n"))
+	return opts
+}
+
+// finish applies the deterministic fixes of the commit flow to the message of a group
+// and lets the AI correct the lint violations.
+func (s *splitSession) finish(ctx context.Context, g splitGroup) string {
+	opts := s.groupOptions(ctx, g)
+	finalize := func(message string) string {
+		if opts.Scope != "" {
+			message = rewriteScope(message, opts.Scope)
+		}
+		return applyTicketRules(message, opts.Branch, s.ticketRules)
+	}
+	diff, fileList := filterDiffFiles(s.diff, g.Files), strings.Join(g.Files, "\n")
+	return repairMessage(finalize(g.Message), s.lintRules, s.cfg.Lint.RepairAttempts, func(previous string, violations []validator.Violation) (string, error) {
+		revised, err := repairCommitMessage(ctx, s.executor, diff, fileList, "", previous, violations, opts)
+		if err != nil {
+			return "", err
+		}
+		return finalize(revised), nil
+	})
+}
+
+// runSplit plans the commits of the staged changes, lets the user adjust the plan and
+// creates the commits. It returns the exit code.
+func runSplit(ctx context.Context, s *splitSession, autoConfirm bool) int {
+	paths, err := stagedPaths(ctx)
+	if err != nil {
+		fmt.Printf("❌ Error: Failed to list the staged files: %v\n", err)
+		return 1
+	}
+	s.paths = paths
+
+	fmt.Printf("🧩 Planning commits for %d staged file(s)...\n", len(paths))
+	groups, err := s.plan(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+			return 1
+		}
+		fmt.Printf("❌ Error: Failed to plan the commits: %v\n", err)
+		printFailureHints(err)
+		return 1
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for confirmed := autoConfirm; !confirmed; {
+		printSplitPlan(groups, s.lintRules)
+
+		fmt.Printf("\nDo you want to create these %d commit(s)? [y/N/m/e/r]: ", len(groups))
+		fmt.Print("\n  y/yes - Create the commits")
+		fmt.Print("\n  n/no  - Cancel")
+		fmt.Print("\n  m/move - Move a file to another commit")
+		fmt.Print("\n  e/edit - Edit the message of a commit in your editor")
+		fmt.Print("\n  r/regenerate - Plan the commits again")
+		fmt.Print("\n\nYour choice: ")
+
+		response, err := readLine(reader)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to read input: %v\n", err)
+			return 1
+		}
+
+		switch strings.ToLower(response) {
+		case "y", "yes":
+			confirmed = true
+		case "n", "no", "":
+			fmt.Println("\n⏹️ Split canceled. The changes are still staged.")
+			return 0
+		case "m", "move":
+			fmt.Printf("\nFile and commit, e.g. \"3 2\" (commit %d starts a new one): ", len(groups)+1)
+			input, err := readLine(reader)
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to read input: %v\n", err)
+				return 1
+			}
+			moved, changed, err := moveSplitFile(groups, input)
+			if err != nil {
+				fmt.Printf("\n⚠️ %v\n", err)
+				continue
+			}
+			fmt.Println("\n🔄 Rewriting the messages of the changed commits...")
+			failed := false
+			for _, i := range changed {
+				message, genErr := s.describe(ctx, moved[i])
+				if ctx.Err() != nil {
+					fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+					return 1
+				}
+				if genErr != nil || message == "" {
+					fmt.Printf("⚠️ Warning: Failed to rewrite the message of commit %d: %v\n", i+1, genErr)
+					failed = failed || moved[i].Message == ""
+					continue
+				}
+				moved[i].Message = message
+			}
+			if failed {
+				fmt.Println("Keeping the previous plan...")
+				continue
+			}
+			reportBackends(s.executor)
+			groups = moved
+		case "e", "edit":
+			fmt.Printf("\nCommit to edit [1-%d]: ", len(groups))
+			input, err := readLine(reader)
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to read input: %v\n", err)
+				return 1
+			}
+			n, convErr := strconv.Atoi(input)
+			if convErr != nil || n < 1 || n > len(groups) {
+				fmt.Printf("\n⚠️ Invalid commit number. Please enter 1-%d.\n", len(groups))
+				continue
+			}
+			edited, editErr := editMessageInEditor(ctx, groups[n-1].Message)
+			if editErr != nil || edited == "" {
+				fmt.Println("\n⚠️ Keeping the generated message...")
+				continue
+			}
+			groups[n-1].Message = edited
+			fmt.Println("\n✏️ Message updated!")
+		case "r", "regenerate":
+			fmt.Println("\n🔄 Planning the commits again...")
+			regenerated, genErr := s.plan(ctx)
+			if genErr != nil {
+				if ctx.Err() != nil {
+					fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+					return 1
+				}
+				fmt.Printf("\n❌ Error: Failed to plan the commits: %v\n", genErr)
+				fmt.Println("Keeping the previous plan...")
+				continue
+			}
+			groups = regenerated
+		default:
+			fmt.Println("\n⚠️ Invalid choice. Please enter y, n, m, e, or r.")
+		}
+	}
+
+	if autoConfirm {
+		printSplitPlan(groups, s.lintRules)
+	}
+	created, err := commitSplitGroups(ctx, groups, gitCommit)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("\n⏹️ Interrupted. Cleaning up...")
+		} else {
+			fmt.Printf("\n❌ Commit %d failed: %v\n", created+1, err)
+		}
+		fmt.Printf("Created %d of %d commit(s); the remaining changes are still staged.\n", created, len(groups))
+		return 1
+	}
+	fmt.Printf("\n✅ Created %d commit(s) successfully!\n", created)
+	return 0
+}
+
+// readLine reads one line of input without the surrounding whitespace. A last line
+// without a newline is accepted.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// printSplitPlan shows the planned commits with their files, numbered across the
+// whole plan so that a file can be moved by its number.
+func printSplitPlan(groups []splitGroup, rules *validator.Config) {
+	fmt.Println("\n🧩 Proposed Commits:")
+	fmt.Println("===================================")
+	n := 0
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Println("-----------------------------------")
+		}
+		fmt.Printf("[%d] %s\n", i+1, strings.ReplaceAll(g.Message, "\n", "\n    "))
+		for _, path := range g.Files {
+			n++
+			fmt.Printf("    %3d. %s\n", n, path)
+		}
+		printViolations(g.Message, rules)
+	}
+	fmt.Println("===================================")
+}
+
+// moveSplitFile moves a file, given by its number in printSplitPlan or its path, to the
+// commit with the given number; the number after the last commit starts a new one. It
+// returns the new plan and the indexes of the commits whose files changed. A commit
+// left without files is removed, and a new commit has no message yet.
+func moveSplitFile(groups []splitGroup, input string) ([]splitGroup, []int, error) {
+	fields := strings.Fields(input)
+	if len(fields) != 2 {
+		return nil, nil, errors.New("enter the file number or path and the commit number, e.g. \"3 2\"")
+	}
+	to, err := strconv.Atoi(fields[1])
+	if err != nil || to < 1 || to > len(groups)+1 {
+		return nil, nil, fmt.Errorf("invalid commit number, please enter 1-%d", len(groups)+1)
+	}
+
+	from, file := -1, -1
+	n, wanted := 0, -1
+	if v, convErr := strconv.Atoi(fields[0]); convErr == nil {
+		wanted = v
+	}
+	for i, g := range groups {
+		for j, path := range g.Files {
+			n++
+			if n == wanted || path == fields[0] {
+				from, file = i, j
+			}
+		}
+	}
+	if from == -1 {
+		return nil, nil, fmt.Errorf("no file %q in the plan", fields[0])
+	}
+	if from == to-1 {
+		return nil, nil, fmt.Errorf("the file is already in commit %d", to)
+	}
+
+	moved := make([]splitGroup, len(groups), len(groups)+1)
+	for i, g := range groups {
+		moved[i] = splitGroup{Message: g.Message, Files: append([]string(nil), g.Files...)}
+	}
+	if to > len(moved) {
+		moved = append(moved, splitGroup{})
+	}
+	path := moved[from].Files[file]
+	moved[from].Files = append(moved[from].Files[:file], moved[from].Files[file+1:]...)
+	moved[to-1].Files = append(moved[to-1].Files, path)
+
+	changed := []int{from, to - 1}
+	if len(moved[from].Files) == 0 {
+		moved = append(moved[:from], moved[from+1:]...)
+		changed = []int{to - 1}
+		if to-1 > from {
+			changed = []int{to - 2}
+		}
+	}
+	return moved, changed, nil
+}
+
+// commitSplitGroups creates one commit per group. The staged tree is saved first; for
+// each group the index is reset to HEAD and only the staged entries of the group's
+// files are taken over from the saved tree, so that the working tree is never touched.
+// It returns the number of commits created. On failure the remaining changes are
+// staged again.
+func commitSplitGroups(ctx context.Context, groups []splitGroup, commit func(ctx context.Context, message string) error) (int, error) {
+	staged, err := runGit(ctx, "", "write-tree")
+	if err != nil {
+		return 0, fmt.Errorf("failed to save the staged changes: %w", err)
+	}
+	listing, err := runGit(ctx, "", "ls-tree", "-r", "-z", staged)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the staged changes: %w", err)
+	}
+	entries := make(map[string]string)
+	for _, entry := range strings.Split(listing, "\x00") {
+		if info, path, ok := strings.Cut(entry, "\t"); ok {
+			entries[path] = info
+		}
+	}
+	// A zero object with mode 0 removes the path from the index
+	removed := "0 " + strings.Repeat("0", len(staged))
+
+	restore := func() {
+		ctx := context.WithoutCancel(ctx)
+		_, _ = runGit(ctx, "", "read-tree", staged)
+		_, _ = runGit(ctx, "", "update-index", "-q", "--refresh")
+	}
+	for i, g := range groups {
+		base := []string{"read-tree", "HEAD"}
+		if _, headErr := runGit(ctx, "", "rev-parse", "--verify", "-q", "HEAD"); headErr != nil {
+			base = []string{"read-tree", "--empty"}
+		}
+		if _, err := runGit(ctx, "", base...); err != nil {
+			restore()
+			return i, err
+		}
+		var info strings.Builder
+		for _, path := range g.Files {
+			entry, ok := entries[path]
+			if !ok {
+				entry = removed
+			}
+			fmt.Fprintf(&info, "%s\t%s\x00", entry, path)
+		}
+		if _, err := runGit(ctx, info.String(), "update-index", "-z", "--index-info"); err != nil {
+			restore()
+			return i, err
+		}
+		fmt.Printf("\n📦 [%d/%d] %s\n", i+1, len(groups), firstLine(g.Message))
+		if err := commit(ctx, g.Message); err != nil {
+			restore()
+			return i, err
+		}
+	}
+	restore()
+	return len(groups), nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSplitPlan(t *testing.T) {
+	paths := []string{"api/login.go", "api/login_test.go", "README.md", "go.sum"}
+	tests := []struct {
+		name       string
+		raw        string
+		want       []splitGroup
+		unassigned []string
+		wantErr    bool
+	}{
+		{
+			name: "every file assigned",
+			raw:  `{"commits": [{"message": "feat(api): add login", "files": ["api/login.go", "api/login_test.go"]}, {"message": "docs: describe login", "files": ["README.md", "go.sum"]}]}`,
+			want: []splitGroup{
+				{Message: "feat(api): add login", Files: []string{"api/login.go", "api/login_test.go"}},
+				{Message: "docs: describe login", Files: []string{"README.md", "go.sum"}},
+			},
+		},
+		{
+			name: "surrounding text, unknown and duplicate files",
+			raw:  "Here is the plan:\n{\"commits\": [{\"message\": \"feat(api): add login\", \"files\": [\"./api/login.go\", \"main.go\"]}, {\"message\": \"test(api): cover login\", \"files\": [\"api/login.go\", \"api/login_test.go\"]}]}\n",
+			want: []splitGroup{
+				{Message: "feat(api): add login", Files: []string{"api/login.go"}},
+				{Message: "test(api): cover login", Files: []string{"api/login_test.go", "README.md", "go.sum"}},
+			},
+			unassigned: []string{"README.md", "go.sum"},
+		},
+		{
+			name: "groups without files or message are dropped",
+			raw:  `{"commits": [{"message": "chore: nothing", "files": []}, {"message": "", "files": ["README.md"]}, {"message": "feat: all", "files": ["go.sum"]}]}`,
+			want: []splitGroup{
+				{Message: "feat: all", Files: []string{"go.sum", "api/login.go", "api/login_test.go", "README.md"}},
+			},
+			unassigned: []string{"api/login.go", "api/login_test.go", "README.md"},
+		},
+		{name: "not JSON", raw: "feat: add login", wantErr: true},
+		{name: "no staged files", raw: `{"commits": [{"message": "feat: x", "files": ["x.go"]}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, unassigned, err := parseSplitPlan(tt.raw, paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSplitPlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(unassigned, tt.unassigned) {
+				t.Errorf("parseSplitPlan() = %q, %q, want %q, %q", got, unassigned, tt.want, tt.unassigned)
+			}
+		})
+	}
+}
+
+func TestMoveSplitFile(t *testing.T) {
+	groups := []splitGroup{
+		{Message: "feat: a", Files: []string{"a.go", "a_test.go"}},
+		{Message: "docs: b", Files: []string{"b.md"}},
+	}
+	tests := []struct {
+		input   string
+		want    []splitGroup
+		changed []int
+		wantErr bool
+	}{
+		{
+			input:   "2 2",
+			want:    []splitGroup{{Message: "feat: a", Files: []string{"a.go"}}, {Message: "docs: b", Files: []string{"b.md", "a_test.go"}}},
+			changed: []int{0, 1},
+		},
+		{
+			input:   "b.md 3",
+			want:    []splitGroup{{Message: "feat: a", Files: []string{"a.go", "a_test.go"}}, {Files: []string{"b.md"}}},
+			changed: []int{1},
+		},
+		{
+			input:   "3 1",
+			want:    []splitGroup{{Message: "feat: a", Files: []string{"a.go", "a_test.go", "b.md"}}},
+			changed: []int{0},
+		},
+		{input: "1 1", wantErr: true},
+		{input: "9 1", wantErr: true},
+		{input: "1 4", wantErr: true},
+		{input: "1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, changed, err := moveSplitFile(groups, tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("moveSplitFile(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(changed, tt.changed) {
+			t.Errorf("moveSplitFile(%q) = %q, %v, want %q, %v", tt.input, got, changed, tt.want, tt.changed)
+		}
+	}
+	if len(groups[0].Files) != 2 || len(groups[1].Files) != 1 {
+		t.Errorf("moveSplitFile() modified the plan it was given: %q", groups)
+	}
+}
+
+func TestMainSplit(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainSplit" {
+		if err := os.Chdir(os.Getenv("SPLIT_REPO")); err != nil {
+			panic(err)
+		}
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				if strings.Contains(prompt, "コミットへ分割する計画") {
+					// old.txt is left out and ends up in the last commit
+					return `{"commits": [{"message": "feat: a.txtを追加", "files": ["a.txt"]}, {"message": "chore: base.txtを更新", "files": ["base.txt"]}]}`, nil
+				}
+				return "chore: まとめて整理", nil
+			}), nil
+		}
+		runPreCommit = func(ctx context.Context) error { return nil }
+		runMainWithStdin(os.Getenv("SPLIT_INPUT"))
+		return
+	}
+
+	setup := func(t *testing.T) string {
+		repo := t.TempDir()
+		gitIn(t, repo, nil, "init", "-b", "main")
+		gitIn(t, repo, nil, "config", "user.name", "Test User")
+		gitIn(t, repo, nil, "config", "user.email", "test@example.com")
+		write := func(name, content string) {
+			if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write("base.txt", "base\n")
+		write("old.txt", "old\n")
+		gitIn(t, repo, nil, "add", ".")
+		gitIn(t, repo, nil, "commit", "-m", "chore: initial")
+		write("base.txt", "base\nstaged\n")
+		write("a.txt", "a\n")
+		gitIn(t, repo, nil, "add", "base.txt", "a.txt")
+		gitIn(t, repo, nil, "rm", "-q", "old.txt")
+		// Unstaged changes are left alone
+		write("base.txt", "base\nstaged\nunstaged\n")
+		return repo
+	}
+	check := func(t *testing.T, repo, wantLog string) {
+		t.Helper()
+		if got := gitIn(t, repo, nil, "log", "--format=%s", "--name-status"); got != wantLog {
+			t.Errorf("history =\n%s\nwant\n%s", got, wantLog)
+		}
+		if got := gitIn(t, repo, nil, "status", "--porcelain"); got != "M base.txt" {
+			t.Errorf("status = %q, want only the unstaged change of base.txt", got)
+		}
+		if content, _ := os.ReadFile(filepath.Join(repo, "base.txt")); string(content) != "base\nstaged\nunstaged\n" {
+			t.Errorf("the working tree was changed: %q", content)
+		}
+	}
+	t.Run("the plan is committed group by group", func(t *testing.T) {
+		repo := setup(t)
+		t.Setenv("SPLIT_REPO", repo)
+		output := runTestSubprocess(t, "TestMainSplit", "-y", "-split")
+		if !strings.Contains(output, "The plan left out 1 file(s), adding them to the last commit: old.txt") || !strings.Contains(output, "Created 2 commit(s) successfully") {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+		check(t, repo, "chore: base.txtを更新\n\nM\tbase.txt\nD\told.txt\nfeat: a.txtを追加\n\nA\ta.txt\nchore: initial\n\nA\tbase.txt\nA\told.txt")
+	})
+
+	t.Run("moving a file rewrites the affected messages", func(t *testing.T) {
+		repo := setup(t)
+		t.Setenv("SPLIT_REPO", repo)
+		t.Setenv("SPLIT_INPUT", "m\n1 2\ny\n")
+		output := runTestSubprocess(t, "TestMainSplit", "-split")
+		if !strings.Contains(output, "Created 1 commit(s) successfully") {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+		check(t, repo, "chore: まとめて整理\n\nA\ta.txt\nM\tbase.txt\nD\told.txt\nchore: initial\n\nA\tbase.txt\nA\told.txt")
+	})
+
+	t.Run("canceling keeps the changes staged", func(t *testing.T) {
+		repo := setup(t)
+		t.Setenv("SPLIT_REPO", repo)
+		t.Setenv("SPLIT_INPUT", "n\n")
+		output := runTestSubprocess(t, "TestMainSplit", "-split")
+		if !strings.Contains(output, "Split canceled") {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+		if got := gitIn(t, repo, nil, "diff", "--staged", "--name-only"); got != "a.txt\nbase.txt\nold.txt" {
+			t.Errorf("staged files = %q", got)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Modes for diffs larger than the token budget.
+const (
+	diffModeTruncate  = "truncate"
+	diffModeSummarize = "summarize"
+)
+
+// Defaults of the summarize mode.
+const (
+	defaultChunkSize   = 20000
+	defaultParallelism = 4
+)
+
+// hunkTruncatedNote marks a hunk that was cut because it alone exceeds the chunk size.
+const hunkTruncatedNote = "\n...(hunk truncated for size)..."
+
+// Validate checks the budgets, the diff mode and the summarize settings.
+func (c DiffConfig) Validate() error {
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("invalid diff.max_tokens: %d", c.MaxTokens)
+	}
+	for name, tokens := range c.Budgets {
+		if tokens <= 0 {
+			return fmt.Errorf("invalid diff.budgets.%s: %d", name, tokens)
+		}
+	}
+	switch c.Mode {
+	case "", diffModeTruncate, diffModeSummarize:
+	default:
+		return fmt.Errorf("invalid diff.mode %q (expected %s or %s)", c.Mode, diffModeTruncate, diffModeSummarize)
+	}
+	if c.ChunkSize <= 0 {
+		return fmt.Errorf("invalid diff.chunk_size: %d", c.ChunkSize)
+	}
+	if c.Parallel <= 0 {
+		return fmt.Errorf("invalid diff.parallel: %d", c.Parallel)
+	}
+	return nil
+}
+
+// shouldSummarize reports whether diff, over budget estimated tokens, is summarized
+// instead of being fitted to the budget.
+func (c DiffConfig) shouldSummarize(diff string, budget int) bool {
+	return c.Mode == diffModeSummarize && estimateTokens(diff) > budget
+}
+
+// splitDiff splits a unified diff into chunks of at most chunkSize bytes. Whole files
+// are kept together when they fit; larger files are split between hunks, repeating
+// the file header in every chunk, and a single hunk larger than chunkSize is cut.
+func splitDiff(diff string, chunkSize int) []string {
+	var pieces []string
+	for _, file := range splitDiffFiles(diff) {
+		if len(file) <= chunkSize {
+			pieces = append(pieces, file)
+			continue
+		}
+		pieces = append(pieces, splitDiffHunks(file, chunkSize)...)
+	}
+
+	var chunks []string
+	var current strings.Builder
+	for _, piece := range pieces {
+		if current.Len() > 0 && current.Len()+len(piece) > chunkSize {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		current.WriteString(piece)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// splitDiffFiles splits a diff at its "diff --git" lines. Each part keeps its newline.
+func splitDiffFiles(diff string) []string {
+	var files []string
+	start := 0
+	for i := 0; i < len(diff); {
+		end := strings.IndexByte(diff[i:], '\n')
+		if end == -1 {
+			break
+		}
+		next := i + end + 1
+		if next < len(diff) && strings.HasPrefix(diff[next:], "diff --git ") {
+			files = append(files, diff[start:next])
+			start = next
+		}
+		i = next
+	}
+	if start < len(diff) {
+		files = append(files, diff[start:])
+	}
+	return files
+}
+
+// splitDiffHunks splits the diff of one file into pieces of at most chunkSize bytes,
+// each starting with the file header.
+func splitDiffHunks(file string, chunkSize int) []string {
+	parsed := parseDiffFile(file)
+	header, hunks := parsed.Header, parsed.Hunks
+
+	// The header alone may not leave room for any content, e.g. for a huge binary patch
+	room := chunkSize - len(header)
+	if room <= len(hunkTruncatedNote) {
+		return []string{truncateDiffPiece(file, chunkSize)}
+	}
+
+	var pieces []string
+	var current strings.Builder
+	for _, h := range hunks {
+		if len(h) > room {
+			h = truncateDiffPiece(h, room)
+		}
+		if current.Len() > 0 && current.Len()+len(h) > room {
+			pieces = append(pieces, header+current.String())
+			current.Reset()
+		}
+		current.WriteString(h)
+	}
+	if current.Len() > 0 || len(pieces) == 0 {
+		pieces = append(pieces, header+current.String())
+	}
+	return pieces
+}
+
+// truncateDiffPiece cuts s to at most size bytes, including the truncation note.
+func truncateDiffPiece(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	return s[:max(size-len(hunkTruncatedNote), 0)] + hunkTruncatedNote
+}
+
+// summarizeDiff splits diff into chunks and asks the executor to summarize each of
+// them, running at most settings.Parallel requests at a time. The summaries are
+// returned in diff order; any failed chunk fails the whole summary because the final
+// message would silently miss part of the change.
+func summarizeDiff(ctx context.Context, executor AIExecutor, diff string, settings DiffConfig, lang string) ([]string, error) {
+	chunks := splitDiff(diff, settings.ChunkSize)
+	text := promptTextFor(lang)
+
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	slots := make(chan struct{}, max(settings.Parallel, 1))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-slots }()
+
+			summary, err := executor.Execute(ctx, fmt.Sprintf(text.summarize, i+1, len(chunks), chunk))
+			if err == nil && strings.TrimSpace(summary) == "" {
+				err = errEmptyResponse
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+				return
+			}
+			summaries[i] = strings.TrimSpace(summary)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
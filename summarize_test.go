@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testFileDiff returns the diff of a file with the given hunk bodies.
+func testFileDiff(name string, hunks ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n", name, name, name, name)
+	for i, hunk := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,1 +%d,1 @@\n%s", i*10+1, i*10+1, hunk)
+	}
+	return b.String()
+}
+
+func TestSplitDiff(t *testing.T) {
+	small1 := testFileDiff("a.go", "+a\n")
+	small2 := testFileDiff("b.go", "+b\n")
+	bigHunk := "+" + strings.Repeat("x", 150) + "\n"
+	big := testFileDiff("big.go", bigHunk, bigHunk, bigHunk)
+	huge := testFileDiff("huge.go", "+"+strings.Repeat("y", 1000)+"\n")
+
+	t.Run("small files share a chunk", func(t *testing.T) {
+		chunks := splitDiff(small1+small2, 1000)
+		if len(chunks) != 1 || chunks[0] != small1+small2 {
+			t.Errorf("splitDiff() = %q", chunks)
+		}
+	})
+
+	t.Run("files are not split across chunks when they fit", func(t *testing.T) {
+		chunks := splitDiff(small1+small2, len(small1)+10)
+		if len(chunks) != 2 || chunks[0] != small1 || chunks[1] != small2 {
+			t.Errorf("splitDiff() = %q", chunks)
+		}
+	})
+
+	t.Run("large file is split between hunks", func(t *testing.T) {
+		chunks := splitDiff(small1+big, 300)
+		header := "diff --git a/big.go b/big.go\n"
+		hunks := 0
+		for _, chunk := range chunks {
+			if len(chunk) > 300 {
+				t.Errorf("chunk of %d bytes exceeds the chunk size", len(chunk))
+			}
+			if strings.Contains(chunk, bigHunk) && !strings.HasPrefix(chunk, header) {
+				t.Errorf("hunk chunk without the file header: %q", chunk)
+			}
+			hunks += strings.Count(chunk, bigHunk)
+		}
+		if hunks != 3 {
+			t.Errorf("expected every hunk once, got %d in %q", hunks, chunks)
+		}
+	})
+
+	t.Run("oversized hunk is truncated", func(t *testing.T) {
+		chunks := splitDiff(huge, 300)
+		if len(chunks) != 1 || len(chunks[0]) > 300 || !strings.HasSuffix(chunks[0], hunkTruncatedNote) {
+			t.Errorf("splitDiff() = %q", chunks)
+		}
+		if !strings.HasPrefix(chunks[0], "diff --git a/huge.go") {
+			t.Errorf("truncated chunk should keep the header, got %q", chunks[0])
+		}
+	})
+}
+
+func TestSummarizeDiff(t *testing.T) {
+	var diff strings.Builder
+	for i := 0; i < 6; i++ {
+		diff.WriteString(testFileDiff(fmt.Sprintf("f%d.go", i), "+x\n"))
+	}
+	settings := DiffConfig{ChunkSize: 100, Parallel: 2}
+	partPattern := regexp.MustCompile(`part (\d+) of (\d+)`)
+
+	var running, peak atomic.Int32
+	executor := funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		m := partPattern.FindStringSubmatch(prompt)
+		return "- summary " + m[1] + "/" + m[2], nil
+	})
+
+	summaries, err := summarizeDiff(context.Background(), executor, diff.String(), settings, "en")
+	if err != nil {
+		t.Fatalf("summarizeDiff() unexpected error = %v", err)
+	}
+	if len(summaries) != 6 {
+		t.Fatalf("summarizeDiff() = %q, want 6 summaries", summaries)
+	}
+	for i, summary := range summaries {
+		if want := fmt.Sprintf("- summary %d/6", i+1); summary != want {
+			t.Errorf("summaries[%d] = %q, want %q", i, summary, want)
+		}
+	}
+	if peak.Load() > 2 {
+		t.Errorf("%d chunks were summarized at once, want at most 2", peak.Load())
+	}
+
+	failing := funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+		if strings.Contains(prompt, "part 3 of") {
+			return "", errors.New("boom")
+		}
+		return "- ok", nil
+	})
+	if _, err := summarizeDiff(context.Background(), failing, diff.String(), settings, "en"); err == nil || !strings.Contains(err.Error(), "chunk 3/6: boom") {
+		t.Errorf("summarizeDiff() error = %v, want the failed chunk", err)
+	}
+}
+
+func TestDiffConfigValidate(t *testing.T) {
+	valid := defaultConfig().Diff
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error = %v", err)
+	}
+	for _, tt := range []DiffConfig{
+		{Mode: "split", ChunkSize: 1, Parallel: 1},
+		{Mode: diffModeSummarize, ChunkSize: 0, Parallel: 1},
+		{Mode: diffModeSummarize, ChunkSize: 1, Parallel: 0},
+		{MaxTokens: -1, ChunkSize: 1, Parallel: 1},
+		{Budgets: map[string]int{"codex": 0}, ChunkSize: 1, Parallel: 1},
+	} {
+		if err := tt.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", tt)
+		}
+	}
+
+	settings := DiffConfig{Mode: diffModeSummarize}
+	if settings.shouldSummarize("short", 10) || !settings.shouldSummarize(strings.Repeat("x", 41), 10) {
+		t.Error("shouldSummarize() should only summarize diffs over the budget")
+	}
+	settings.Mode = diffModeTruncate
+	if settings.shouldSummarize(strings.Repeat("x", 41), 10) {
+		t.Error("shouldSummarize() should not summarize in truncate mode")
+	}
+}
+
+func TestBuildCommitPromptSummaries(t *testing.T) {
+	for _, lang := range []string{"ja", "en"} {
+		prompt, err := buildCommitPrompt("secret diff body", "a.go", "stat", promptOptions{Language: lang, Summaries: []string{"- first part", "- second part"}})
+		if err != nil {
+			t.Fatalf("buildCommitPrompt() unexpected error = %v", err)
+		}
+		if strings.Contains(prompt, "secret diff body") || !strings.Contains(prompt, "---\n- first part\n---\n- second part\n---") {
+			t.Errorf("%s prompt should contain the summaries instead of the diff, got:\n%s", lang, prompt)
+		}
+	}
+}
+
+func TestMainSummarizeLargeDiff(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainSummarizeLargeDiff" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+		config := "[diff]\nmax_tokens = 3\nmode = \"summarize\"\nchunk_size = 100\n"
+		if err := os.WriteFile(repoConfigFile, []byte(config), 0o644); err != nil {
+			panic(err)
+		}
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				if strings.Contains(prompt, "git diffの一部") {
+					return "- test.txtを追加", nil
+				}
+				if !strings.Contains(prompt, "差分の要約:\n---\n- test.txtを追加\n---") {
+					return "", errors.New("summaries missing from the prompt")
+				}
+				return "feat: 要約から生成", nil
+			}), nil
+		}
+		runPreCommit = func(ctx context.Context) error { return nil }
+		runMainWithStdin("")
+		printLastCommitSubject()
+		return
+	}
+
+	output := runTestSubprocess(t, "TestMainSummarizeLargeDiff", "-y")
+	if !strings.Contains(output, "Summarized the diff in") || !strings.Contains(output, "last commit: feat: 要約から生成") {
+		t.Errorf("Expected the message to be written from the summaries, got '%s'", output)
+	}
+}
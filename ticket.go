@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Placements of ticket IDs in the commit message.
+const (
+	ticketPlacementSubject = "subject"
+	ticketPlacementScope   = "scope"
+	ticketPlacementFooter  = "footer"
+)
+
+// defaultTicketFooter is the footer token used by the footer placement.
+const defaultTicketFooter = "Refs"
+
+// conventionalHeaderPattern splits a conventional commit subject into type, scope,
+// breaking marker and description.
+var conventionalHeaderPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^)]*)\))?(!)?:\s*(.*)$`)
+
+// TicketRule extracts ticket IDs from the branch name and makes sure they appear in
+// the commit message.
+type TicketRule struct {
+	// Pattern matches ticket IDs in the branch name. The first capture group is used
+	// when the pattern has one, otherwise the whole match.
+	Pattern string `toml:"pattern"`
+	// Placement is where the IDs go: "subject" (before the description), "scope" or "footer".
+	Placement string `toml:"placement"`
+	// Footer is the footer token for the footer placement; defaults to "Refs".
+	Footer string `toml:"footer"`
+}
+
+// ticketRule is a TicketRule with its pattern compiled.
+type ticketRule struct {
+	pattern   *regexp.Regexp
+	placement string
+	footer    string
+}
+
+// compileTicketRules validates and compiles the configured rules.
+func compileTicketRules(rules []TicketRule) ([]*ticketRule, error) {
+	compiled := make([]*ticketRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("tickets[%d]: pattern is required", i)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("tickets[%d]: invalid pattern: %w", i, err)
+		}
+
+		placement := rule.Placement
+		switch placement {
+		case "":
+			placement = ticketPlacementFooter
+		case ticketPlacementSubject, ticketPlacementScope, ticketPlacementFooter:
+		default:
+			return nil, fmt.Errorf("tickets[%d]: invalid placement %q (expected %s, %s or %s)",
+				i, rule.Placement, ticketPlacementSubject, ticketPlacementScope, ticketPlacementFooter)
+		}
+
+		footer := rule.Footer
+		if footer == "" {
+			footer = defaultTicketFooter
+		}
+		compiled = append(compiled, &ticketRule{pattern: pattern, placement: placement, footer: footer})
+	}
+	return compiled, nil
+}
+
+// extract returns the distinct ticket IDs found in branch, in order of appearance.
+func (r *ticketRule) extract(branch string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, match := range r.pattern.FindAllStringSubmatch(branch, -1) {
+		id := match[0]
+		if len(match) > 1 && match[1] != "" {
+			id = match[1]
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// applyTicketRules adds the ticket IDs found in branch to message according to each
+// rule. IDs that are already in the requested place are left alone.
+func applyTicketRules(message, branch string, rules []*ticketRule) string {
+	if branch == "" || message == "" {
+		return message
+	}
+	for _, rule := range rules {
+		ids := rule.extract(branch)
+		if len(ids) == 0 {
+			continue
+		}
+		switch rule.placement {
+		case ticketPlacementSubject:
+			message = placeTicketsInSubject(message, ids)
+		case ticketPlacementScope:
+			message = placeTicketsInScope(message, ids)
+		case ticketPlacementFooter:
+			message = placeTicketsInFooter(message, rule.footer, ids)
+		}
+	}
+	return message
+}
+
+// missingTickets returns the ids that do not occur in text.
+func missingTickets(text string, ids []string) []string {
+	var missing []string
+	for _, id := range ids {
+		if !strings.Contains(text, id) {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// placeTicketsInSubject prefixes the description with the missing IDs, e.g.
+// "feat(auth): PROJ-1 add login". Subjects that are not conventional get the prefix at the start.
+func placeTicketsInSubject(message string, ids []string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+	missing := missingTickets(subject, ids)
+	if len(missing) == 0 {
+		return message
+	}
+
+	prefix := strings.Join(missing, " ") + " "
+	if m := conventionalHeaderPattern.FindStringSubmatchIndex(subject); m != nil {
+		// m[8] is the start of the description
+		subject = subject[:m[8]] + prefix + subject[m[8]:]
+	} else {
+		subject = prefix + subject
+	}
+
+	if hasRest {
+		return subject + "\n" + rest
+	}
+	return subject
+}
+
+// placeTicketsInScope adds the missing IDs to the scope, e.g. "feat(auth,PROJ-1): add login".
+// Subjects that are not conventional fall back to the subject placement.
+func placeTicketsInScope(message string, ids []string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+	m := conventionalHeaderPattern.FindStringSubmatch(subject)
+	if m == nil {
+		return placeTicketsInSubject(message, ids)
+	}
+	typ, scope, bang, description := m[1], m[2], m[3], m[4]
+
+	missing := missingTickets(scope, ids)
+	if len(missing) == 0 {
+		return message
+	}
+	scopes := missing
+	if scope != "" {
+		scopes = append([]string{scope}, missing...)
+	}
+	subject = fmt.Sprintf("%s(%s)%s: %s", typ, strings.Join(scopes, ","), bang, description)
+
+	if hasRest {
+		return subject + "\n" + rest
+	}
+	return subject
+}
+
+// placeTicketsInFooter adds the missing IDs to the "<token>: " footer, creating it
+// at the end of the message when there is none.
+func placeTicketsInFooter(message, token string, ids []string) string {
+	prefix := token + ": "
+	lines := strings.Split(message, "\n")
+	footerLine := -1
+	for i, line := range lines {
+		if i > 0 && strings.HasPrefix(line, prefix) {
+			footerLine = i
+		}
+	}
+
+	if footerLine == -1 {
+		return strings.TrimRight(message, "\n") + "\n\n" + prefix + strings.Join(ids, ", ")
+	}
+
+	missing := missingTickets(lines[footerLine], ids)
+	if len(missing) == 0 {
+		return message
+	}
+	lines[footerLine] += ", " + strings.Join(missing, ", ")
+	return strings.Join(lines, "\n")
+}
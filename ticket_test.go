@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCompileTicketRules(t *testing.T) {
+	rules, err := compileTicketRules([]TicketRule{{Pattern: `[A-Z]+-\d+`}})
+	if err != nil {
+		t.Fatalf("compileTicketRules() unexpected error = %v", err)
+	}
+	if rules[0].placement != ticketPlacementFooter || rules[0].footer != defaultTicketFooter {
+		t.Errorf("unexpected defaults: %+v", rules[0])
+	}
+
+	errorTests := []struct {
+		rule          TicketRule
+		errorContains string
+	}{
+		{TicketRule{}, "pattern is required"},
+		{TicketRule{Pattern: `(`}, "invalid pattern"},
+		{TicketRule{Pattern: `\d+`, Placement: "body"}, `invalid placement "body"`},
+	}
+	for _, tt := range errorTests {
+		_, err := compileTicketRules([]TicketRule{{Pattern: `x`}, tt.rule})
+		if err == nil || !strings.Contains(err.Error(), "tickets[1]: "+tt.errorContains) {
+			t.Errorf("compileTicketRules(%+v) error = %v, want %s", tt.rule, err, tt.errorContains)
+		}
+	}
+}
+
+func TestApplyTicketRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []TicketRule
+		branch  string
+		message string
+		want    string
+	}{
+		{
+			name:    "footer is added",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`}},
+			branch:  "feature/PROJ-1234-add-login",
+			message: "feat(auth): add login\n\nbody",
+			want:    "feat(auth): add login\n\nbody\n\nRefs: PROJ-1234",
+		},
+		{
+			name:    "existing footer is extended",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`, Footer: "Closes"}},
+			branch:  "PROJ-1-PROJ-2",
+			message: "fix: crash\n\nCloses: PROJ-1",
+			want:    "fix: crash\n\nCloses: PROJ-1, PROJ-2",
+		},
+		{
+			name:    "footer already complete",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`}},
+			branch:  "feature/PROJ-1234-add-login",
+			message: "feat: add login\n\nRefs: PROJ-1234",
+			want:    "feat: add login\n\nRefs: PROJ-1234",
+		},
+		{
+			name:    "subject prefix",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`, Placement: "subject"}},
+			branch:  "feature/PROJ-1234-add-login",
+			message: "feat(auth)!: add login\n\nbody",
+			want:    "feat(auth)!: PROJ-1234 add login\n\nbody",
+		},
+		{
+			name:    "subject of a non-conventional message",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`, Placement: "subject"}},
+			branch:  "PROJ-9",
+			message: "Add login",
+			want:    "PROJ-9 Add login",
+		},
+		{
+			name:    "subject already has the ticket",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`, Placement: "subject"}},
+			branch:  "PROJ-9",
+			message: "feat: [PROJ-9] add login",
+			want:    "feat: [PROJ-9] add login",
+		},
+		{
+			name:    "scope without scope",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`, Placement: "scope"}},
+			branch:  "bugfix/PROJ-7",
+			message: "fix: crash",
+			want:    "fix(PROJ-7): crash",
+		},
+		{
+			name:    "scope appended to existing scope",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`, Placement: "scope"}},
+			branch:  "bugfix/PROJ-7",
+			message: "fix(api)!: crash\n\nbody",
+			want:    "fix(api,PROJ-7)!: crash\n\nbody",
+		},
+		{
+			name:    "capture group",
+			rules:   []TicketRule{{Pattern: `issue-(\d+)`, Placement: "footer", Footer: "Refs"}},
+			branch:  "feature/issue-42-x",
+			message: "feat: x",
+			want:    "feat: x\n\nRefs: 42",
+		},
+		{
+			name:    "no ticket in branch",
+			rules:   []TicketRule{{Pattern: `[A-Z]+-\d+`}},
+			branch:  "main",
+			message: "feat: x",
+			want:    "feat: x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := compileTicketRules(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := applyTicketRules(tt.message, tt.branch, rules); got != tt.want {
+				t.Errorf("applyTicketRules() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMainTicketFromBranch(t *testing.T) {
+	if os.Getenv("BE_CRASHER") == "1" && os.Getenv("TEST_NAME") == "TestMainTicketFromBranch" {
+		cleanup := setupSubprocessRepo()
+		defer cleanup()
+		if err := exec.Command("git", "checkout", "-q", "-b", "feature/PROJ-1234-add-login").Run(); err != nil {
+			panic(err)
+		}
+		config := "[[tickets]]\npattern = '[A-Z]+-\\d+'\nplacement = \"scope\"\n"
+		if err := os.WriteFile(repoConfigFile, []byte(config), 0o644); err != nil {
+			panic(err)
+		}
+		newExecutor = func(model string, cfg *Config, accept acceptFunc) (AIExecutor, error) {
+			return funcExecutor(func(ctx context.Context, prompt string) (string, error) {
+				return "feat: add login", nil
+			}), nil
+		}
+		runPreCommit = func(ctx context.Context) error { return nil }
+		runMainWithStdin("")
+		printLastCommitSubject()
+		return
+	}
+
+	output := runTestSubprocess(t, "TestMainTicketFromBranch", "-y")
+	if !strings.Contains(output, "last commit: feat(PROJ-1234): add login") {
+		t.Errorf("Expected the ticket in the committed scope, got '%s'", output)
+	}
+}
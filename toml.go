@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseTOML parses src into nested maps. Tables become map[string]any and
+// arrays of tables become []map[string]any.
+func parseTOML(src string) (map[string]any, error) {
+	table := map[string]any{}
+	if _, err := toml.Decode(src, &table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decodeTOML stores the values of table into the struct pointed to by dst,
+// matching keys against `toml` struct tags. Values already present in dst are
+// kept unless table overrides them, so several files can be decoded in turn.
+// If visit is non-nil it is called with the dotted key of every value stored.
+func decodeTOML(table map[string]any, dst any, visit func(key string)) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("decodeTOML requires a pointer to a struct")
+	}
+	if visit == nil {
+		visit = func(string) {}
+	}
+	return decodeTOMLStruct(table, v.Elem(), "", visit)
+}
+
+func decodeTOMLStruct(table map[string]any, v reflect.Value, prefix string, visit func(string)) error {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := tomlField(v, key)
+		if !ok {
+			return fmt.Errorf("unknown key %q", prefix+key)
+		}
+		if err := decodeTOMLValue(table[key], field, prefix+key, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tomlField finds the struct field tagged with key.
+func tomlField(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+		if name == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func decodeTOMLValue(value any, v reflect.Value, key string, visit func(string)) error {
+	if v.Kind() == reflect.Struct || v.Kind() == reflect.Map {
+		return decodeTOMLTable(value, v, key, visit)
+	}
+	if err := decodeTOMLScalar(value, v, key); err != nil {
+		return err
+	}
+	visit(key)
+	return nil
+}
+
+// decodeTOMLTable decodes a table into a struct or a map of structs.
+func decodeTOMLTable(value any, v reflect.Value, key string, visit func(string)) error {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("invalid value for %q: expected table, got %T", key, value)
+	}
+	if v.Kind() == reflect.Struct {
+		return decodeTOMLStruct(table, v, key+".", visit)
+	}
+
+	if v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("unsupported config field type %s for %q", v.Type(), key)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	names := make([]string, 0, len(table))
+	for name := range table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(reflect.ValueOf(name)); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := decodeTOMLValue(table[name], elem, key+"."+name, visit); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(name), elem)
+	}
+	return nil
+}
+
+// decodeTOMLScalar decodes strings, numbers, booleans, durations and arrays.
+func decodeTOMLScalar(value any, v reflect.Value, key string) error {
+	mismatch := func() error {
+		return fmt.Errorf("invalid value for %q: expected %s, got %T", key, tomlTypeName(v.Type()), value)
+	}
+
+	if v.Type() == durationType {
+		switch val := value.(type) {
+		case string:
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("invalid duration for %q: %w", key, err)
+			}
+			v.SetInt(int64(d))
+		case int64:
+			v.SetInt(val * int64(time.Second))
+		default:
+			return mismatch()
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, ok := value.(int64)
+		if !ok {
+			return mismatch()
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		switch n := value.(type) {
+		case float64:
+			v.SetFloat(n)
+		case int64:
+			v.SetFloat(float64(n))
+		default:
+			return mismatch()
+		}
+	case reflect.Slice:
+		var items []any
+		switch list := value.(type) {
+		case []any:
+			items = list
+		case []map[string]any:
+			for _, item := range list {
+				items = append(items, item)
+			}
+		default:
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeTOMLValue(item, slice.Index(i), fmt.Sprintf("%s[%d]", key, i), func(string) {}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported config field type %s for %q", v.Type(), key)
+	}
+	return nil
+}
+
+func tomlTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice:
+		return "array"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "number"
+	default:
+		return t.Kind().String()
+	}
+}
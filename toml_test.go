@@ -0,0 +1,202 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTOML(t *testing.T) {
+	src := `# comment
+title = "gcauto" # trailing comment
+count = 1_000
+ratio = 0.5
+enabled = true
+literal = 'C:\path'
+escaped = "tab\there \"quoted\" \u00e9"
+list = [
+  "a", # first
+  "b",
+]
+instructions = """
+Write the subject in the imperative.
+Mention the ticket \
+when there is one."""
+pattern = '''^\d+ files?$'''
+
+[commands.aider]
+command = "aider"
+args = ["--message", "{prompt}"]
+
+[a."quoted key".b]
+x = 1
+'openai@gpt-4o' = -2.5
+
+[[rules]]
+name = "first"
+
+[[rules]]
+name = "second"
+`
+	got, err := parseTOML(src)
+	if err != nil {
+		t.Fatalf("parseTOML() unexpected error = %v", err)
+	}
+
+	want := map[string]any{
+		"title":        "gcauto",
+		"count":        int64(1000),
+		"ratio":        0.5,
+		"enabled":      true,
+		"literal":      `C:\path`,
+		"escaped":      "tab\there \"quoted\" é",
+		"list":         []any{"a", "b"},
+		"instructions": "Write the subject in the imperative.\nMention the ticket when there is one.",
+		"pattern":      `^\d+ files?$`,
+		"commands": map[string]any{
+			"aider": map[string]any{
+				"command": "aider",
+				"args":    []any{"--message", "{prompt}"},
+			},
+		},
+		"a": map[string]any{
+			"quoted key": map[string]any{
+				"b": map[string]any{
+					"x":             int64(1),
+					"openai@gpt-4o": -2.5,
+				},
+			},
+		},
+		"rules": []map[string]any{
+			{"name": "first"},
+			{"name": "second"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTOML() = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		errorContains string
+	}{
+		{"duplicate key", "a = 1\na = 2\n", "line 2"},
+		{"duplicate table", "[t]\na = 1\n[u]\nb = 1\n[t]\nc = 2\n", "line 5"},
+		{"duplicate dotted key", "[t]\na = 1\n[x]\n[root]\nt = 1\nt.a = 2\n", "line 6"},
+		{"key redefined as a table", "a = 1\n[a]\n", "line 2"},
+		{"unterminated string", "a = \"abc\n", "line 1"},
+		{"unterminated literal string", "a = 'abc\nb = 1\n", "line 1"},
+		{"unterminated multi-line string", "a = \"\"\"\nabc\n", "line 2"},
+		{"unterminated string in multi-line array", "a = [\n  \"x\",\n  \"y\n]\n", "line 3"},
+		{"invalid escape", `a = "\q"`, "invalid escape"},
+		{"missing equals", "a 1\n", "line 1"},
+		{"garbage after value", "a = 1 2\n", "line 1"},
+		{"unterminated array", `a = ["1", "2"`, "line 1"},
+		{"missing comma in multi-line array", "a = [\n  \"x\"\n  \"y\"\n]\n", "line 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML(tt.src)
+			if err == nil {
+				t.Fatal("parseTOML() expected error but got none")
+			}
+			if !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("parseTOML() error = %v, want error containing %s", err, tt.errorContains)
+			}
+		})
+	}
+}
+
+func TestDecodeTOML(t *testing.T) {
+	type item struct {
+		Name string `toml:"name"`
+	}
+	type nested struct {
+		Flag  bool          `toml:"flag"`
+		Delay time.Duration `toml:"delay"`
+	}
+	type target struct {
+		Name    string            `toml:"name"`
+		Size    int               `toml:"size"`
+		Ratio   float64           `toml:"ratio"`
+		Tags    []string          `toml:"tags"`
+		Nested  nested            `toml:"nested"`
+		Items   []item            `toml:"items"`
+		ByName  map[string]nested `toml:"by_name"`
+		Untaged string
+	}
+
+	table, err := parseTOML(`
+name = "x"
+size = 3
+ratio = 1
+tags = ["a", "b"]
+[nested]
+delay = "1500ms"
+[by_name.first]
+flag = true
+[[items]]
+name = "one"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := target{
+		Nested: nested{Flag: true},
+		ByName: map[string]nested{"first": {Delay: time.Second}},
+	}
+	var keys []string
+	if err := decodeTOML(table, &got, func(key string) { keys = append(keys, key) }); err != nil {
+		t.Fatalf("decodeTOML() unexpected error = %v", err)
+	}
+
+	want := target{
+		Name:   "x",
+		Size:   3,
+		Ratio:  1,
+		Tags:   []string{"a", "b"},
+		Nested: nested{Flag: true, Delay: 1500 * time.Millisecond},
+		Items:  []item{{Name: "one"}},
+		ByName: map[string]nested{"first": {Flag: true, Delay: time.Second}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeTOML() = %+v, want %+v", got, want)
+	}
+	sort.Strings(keys)
+	wantKeys := []string{"by_name.first.flag", "items", "name", "nested.delay", "ratio", "size", "tags"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("decodeTOML() visited %q, want %q", keys, wantKeys)
+	}
+
+	errorTests := []struct {
+		src           string
+		errorContains string
+	}{
+		{"unknown = 1", `unknown key "unknown"`},
+		{"[nested]\nmissing = 1", `unknown key "nested.missing"`},
+		{"size = \"3\"", `invalid value for "size"`},
+		{"[nested]\ndelay = \"soon\"", `invalid duration for "nested.delay"`},
+		{"tags = \"a\"", `invalid value for "tags"`},
+		{"tags = [1, 2]", `invalid value for "tags[0]"`},
+		{"name = 2026-01-01T00:00:00Z", `invalid value for "name"`},
+		{"nested = { flag = 1 }", `invalid value for "nested.flag"`},
+	}
+	for _, tt := range errorTests {
+		table, err := parseTOML(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dst target
+		err = decodeTOML(table, &dst, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+			t.Errorf("decodeTOML(%q) error = %v, want error containing %s", tt.src, err, tt.errorContains)
+		}
+	}
+}